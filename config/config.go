@@ -2,17 +2,74 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/patterns"
 
 	"github.com/spf13/viper"
 )
 
+// Placeholder of seed id in batch output path
+const BatchIdPlaceholder = "{{id}}"
+
+// Environment variable with secret of pseudonymize masks by default
+const DefaultPseudonymSecretEnv = "DB_PART_DUMP_SECRET"
+
 var AllowedDbTypes map[string]bool = map[string]bool{
 	"postgres": true,
 }
 
+var AllowedRuleDirections map[string]bool = map[string]bool{
+	constants.OUTGOING: true,
+	constants.INCOMING: true,
+	constants.BOTH:     true,
+	constants.NONE:     true,
+	constants.OWNED:    true,
+}
+
+var AllowedDepthOverflows map[string]bool = map[string]bool{
+	constants.FOLLOW:  true,
+	constants.NULLIFY: true,
+}
+
+var AllowedExcludedReferences map[string]bool = map[string]bool{
+	constants.FAIL:    true,
+	constants.NULLIFY: true,
+}
+
+var AllowedSelections map[string]bool = map[string]bool{
+	constants.RANDOM: true,
+	constants.NEWEST: true,
+	constants.OLDEST: true,
+}
+
+var AllowedSampleMethods map[string]bool = map[string]bool{
+	constants.BERNOULLI: true,
+	constants.SYSTEM:    true,
+	constants.RANDOM:    true,
+}
+
+var AllowedMaskMethods map[string]bool = map[string]bool{
+	constants.MASK_NULL:         true,
+	constants.MASK_CONSTANT:     true,
+	constants.MASK_HASH:         true,
+	constants.MASK_EMAIL:        true,
+	constants.MASK_NAME:         true,
+	constants.MASK_PARTIAL:      true,
+	constants.MASK_RANGE:        true,
+	constants.MASK_PSEUDONYMIZE: true,
+}
+
+var AllowedDirections map[string]bool = map[string]bool{
+	constants.OUTGOING: true,
+	constants.INCOMING: true,
+	constants.OWNED:    true,
+}
+
 type Database struct {
 	DBType          string        `mapstructure:"db_type"`
 	Host            string        `mapstructure:"host"`
@@ -26,21 +83,174 @@ type Database struct {
 	SSLMode         string        `mapstructure:"ssl_mode"`
 }
 type Filter struct {
-	Name  string `mapstructure:"name"`
-	Value string `mapstructure:"value"`
+	Name   string   `mapstructure:"name"`
+	Value  string   `mapstructure:"value"`
+	File   string   `mapstructure:"file"` // CSV or newline-delimited file with values, - is stdin
+	Values []string `mapstructure:"-"`    // Values loaded from file
+}
+
+// Random sample of starting table rows
+type Sample struct {
+	Rows   int    `mapstructure:"rows"`   // Count of sampled rows
+	Method string `mapstructure:"method"` // bernoulli, system, random
+	Seed   int64  `mapstructure:"seed"`   // Seed for reproducible sample
 }
+
 type Table struct {
-	Name    string   `mapstructure:"name"`
-	Filters []Filter `mapstructure:"filters"`
+	Name      string   `mapstructure:"name"`
+	Filters   []Filter `mapstructure:"filters"`
+	Where     string   `mapstructure:"where"`      // Extra row predicate combined with filters
+	MaxDepth  int      `mapstructure:"max_depth"`  // Overrides settings max_depth for starting table
+	Sample    Sample   `mapstructure:"sample"`     // Sample of rows matching filters and where
+	Query     string   `mapstructure:"query"`      // SQL query returning pk values of starting rows
+	QueryFile string   `mapstructure:"query_file"` // Path to .sql file with query
+	BaseTable string   `mapstructure:"base_table"` // Base table of view, traversal starts from its rows
+	KeyColumn string   `mapstructure:"key_column"` // View column with base table pk values, base table pk name by default
+	// Follow only outgoing fks from rows of table, e.g. tenant tables
+	OutgoingOnly bool `mapstructure:"outgoing_only"`
+}
+
+// Virtual fk which is not declared in database
+type Relation struct {
+	Name          string `mapstructure:"name"` // Used as constraint name in rules
+	Table         string `mapstructure:"table"`
+	Column        string `mapstructure:"column"`
+	ForeignTable  string `mapstructure:"foreign_table"`
+	ForeignColumn string `mapstructure:"foreign_column"`
+}
+
+// Rule to choose fks of table to follow.
+// Rule without constraint and foreign_table applies to all table fks
+type Rule struct {
+	Table        string `mapstructure:"table"`
+	Constraint   string `mapstructure:"constraint"`
+	ForeignTable string `mapstructure:"foreign_table"`
+	Direction    string `mapstructure:"direction"` // outgoing, incoming, both, none, owned
+	Where        string `mapstructure:"where"`     // Row predicate for rows of foreign table reached by fk
+	Nullify      bool   `mapstructure:"nullify"`   // Do not follow nullable outgoing fk and set NULL instead
+	// Max count of referencing rows per referenced row for incoming fk, 0 is unlimited
+	MaxChildrenPerParent int    `mapstructure:"max_children_per_parent"`
+	OrderBy              string `mapstructure:"order_by"` // Order of referencing rows kept by max_children_per_parent
+}
+
+// Settings of table reached by traversal
+type TableSettings struct {
+	Name      string `mapstructure:"name"`
+	MaxRows   int    `mapstructure:"max_rows"`  // Max count of dumped rows, 0 is unlimited
+	Selection string `mapstructure:"selection"` // random, newest, oldest. How to choose rows to keep
+	OrderBy   string `mapstructure:"order_by"`  // Column for newest and oldest selection, pk by default
+	Seed      int64  `mapstructure:"seed"`      // Seed for random selection
+	// Predicates combined with AND on every query of table rows, e.g. deleted_at IS NULL
+	DefaultFilters []string `mapstructure:"default_filters"`
+}
+
+// Masking rule of column values in exported rows
+type Mask struct {
+	Table  string  `mapstructure:"table"`
+	Column string  `mapstructure:"column"`
+	Method string  `mapstructure:"method"` // null, constant, hash, email, name, partial, range, pseudonymize
+	Value  string  `mapstructure:"value"`  // Value for constant method
+	Keep   int     `mapstructure:"keep"`   // Count of last not masked characters for partial method, 4 by default
+	Min    float64 `mapstructure:"min"`    // Min value for range method
+	Max    float64 `mapstructure:"max"`    // Max value for range method
+}
+
+// Target size of dump. Seed rows of root tables are added or trimmed until dump fits size
+type Budget struct {
+	Tables  []string `mapstructure:"tables"`  // Root tables where seed rows are picked
+	Percent float64  `mapstructure:"percent"` // Percent of schema size
+	Size    string   `mapstructure:"size"`    // Size with unit, e.g. 500MB, 5GB
+	Seed    int64    `mapstructure:"seed"`    // Seed for random choice of seed rows
+}
+
+func (b Budget) IsEnabled() bool {
+	return len(b.Tables) != 0
+}
+
+// Shift of date and timestamp columns by offset derived from starting row which row was reached from
+type DateShift struct {
+	MaxDays int   `mapstructure:"max_days"` // Max count of days of offset in both directions
+	Seed    int64 `mapstructure:"seed"`     // Seed of offsets, the same seed gives the same offsets
+}
+
+func (s DateShift) IsEnabled() bool {
+	return s.MaxDays != 0
+}
+
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// Parse size with unit, e.g. 5GB, 512MB or 1024
+func ParseSize(size string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(size))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("wrong size %s", size)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+// Separate dump for every seed id of table
+type Batch struct {
+	Table       string   `mapstructure:"table"`       // Table of seed ids
+	Column      string   `mapstructure:"column"`      // Column of seed ids, table pk by default
+	Ids         []string `mapstructure:"ids"`         // Seed ids
+	IdsFile     string   `mapstructure:"ids_file"`    // CSV or newline-delimited file with seed ids, - is stdin
+	Output      string   `mapstructure:"output"`      // Output path template with {{id}}, e.g. backups/user_{{id}}.sql
+	Concurrency int      `mapstructure:"concurrency"` // Count of seeds dumped at the same time
+}
+
+func (b Batch) IsEnabled() bool {
+	return b.Table != ""
 }
 
 type Settings struct {
-	Output                string   `mapstructure:"output"`
-	Format                string   `mapstructure:"format"` // json, sql, or both
-	SchemaName            string   `mapstructure:"schema_name"`
-	Tables                []Table  `mapstructure:"tables"`
-	Direction             string   `mapstructure:"direction"`               // outgoing, incoming
-	IncludeIncomingTables []string `mapstructure:"include_incoming_tables"` // Slice of table name for which do search to incoming fks
+	Output                string          `mapstructure:"output"`
+	Format                string          `mapstructure:"format"` // json, sql, or both
+	SchemaName            string          `mapstructure:"schema_name"`
+	Tables                []Table         `mapstructure:"tables"`
+	Direction             string          `mapstructure:"direction"`               // outgoing, incoming, owned
+	IncludeIncomingTables []string        `mapstructure:"include_incoming_tables"` // Table name patterns for which do search to incoming fks
+	IncludeTables         []string        `mapstructure:"include_tables"`          // Table name patterns allowed for traversal, empty allows all
+	ExcludeTables         []string        `mapstructure:"exclude_tables"`          // Table name patterns not allowed for traversal
+	StopTables            []string        `mapstructure:"stop_tables"`             // Table name patterns which rows are dumped without following fks
+	ExcludedReference     string          `mapstructure:"excluded_reference"`      // fail, nullify. How to handle fks to excluded tables
+	FullTables            []string        `mapstructure:"full_tables"`             // Table name patterns which are dumped completely
+	FullTablesMaxRows     int64           `mapstructure:"full_tables_max_rows"`    // Dump completely tables with estimated rows count up to it
+	TableSettings         []TableSettings `mapstructure:"table_settings"`          // Per table settings
+	Relations             []Relation      `mapstructure:"relations"`               // Virtual fks used together with database fks
+	InferRelations        bool            `mapstructure:"infer_relations"`         // Add fks inferred from column naming
+	Rules                 []Rule          `mapstructure:"rules"`                   // Per table and per fk directions
+	FollowOneToOne        bool            `mapstructure:"follow_one_to_one"`       // Follow incoming one-to-one fks without rules, true by default
+	NullifyNullableFks    bool            `mapstructure:"nullify_nullable_fks"`    // Do not follow nullable outgoing fks and set NULL instead
+	MaxDepth              int             `mapstructure:"max_depth"`               // Max count of fks from starting rows, 0 is unlimited
+	DepthOverflow         string          `mapstructure:"depth_overflow"`          // follow, nullify. How to handle outgoing fks after max_depth
+	Budget                Budget          `mapstructure:"budget"`                  // Target size of dump instead of starting tables filters
+	Masks                 []Mask          `mapstructure:"masks"`                   // Masking rules of column values
+	PseudonymSecretEnv    string          `mapstructure:"pseudonym_secret_env"`    // Environment variable with HMAC secret of hash, email, name, range and pseudonymize masks
+	DateShift             DateShift       `mapstructure:"date_shift"`              // Shift dates of rows by offset of starting row
+	ScanPii               bool            `mapstructure:"scan_pii"`                // Report columns of dumped tables with likely PII without masks
+	PiiSampleSize         int             `mapstructure:"pii_sample_size"`         // Count of sampled rows of every table for PII scan, 100 by default
+	RequireMasking        bool            `mapstructure:"require_masking"`         // Fail before export when PII columns have no masks
+	Batch                 Batch           `mapstructure:"batch"`                   // One dump per seed id instead of one dump of starting tables
+	TenantColumn          string          `mapstructure:"tenant_column"`           // Column of tenant id. Every table with it is starting table
+	Tenant                string          `mapstructure:"tenant"`                  // Tenant id which rows are dumped
 }
 
 type Config struct {
@@ -52,6 +262,128 @@ func (c *Config) Validate() error {
 	if _, ok := AllowedDbTypes[c.Database.DBType]; !ok {
 		return fmt.Errorf("no supported db type %s", c.Database.DBType)
 	}
+	if _, ok := AllowedDirections[c.Settings.Direction]; !ok {
+		return fmt.Errorf("no supported direction %s", c.Settings.Direction)
+	}
+	if _, ok := AllowedDepthOverflows[c.Settings.DepthOverflow]; !ok {
+		return fmt.Errorf("no supported depth overflow %s", c.Settings.DepthOverflow)
+	}
+	if _, ok := AllowedExcludedReferences[c.Settings.ExcludedReference]; !ok {
+		return fmt.Errorf("no supported excluded reference %s", c.Settings.ExcludedReference)
+	}
+	tablePatterns := [][]string{
+		c.Settings.IncludeIncomingTables,
+		c.Settings.IncludeTables,
+		c.Settings.ExcludeTables,
+		c.Settings.StopTables,
+		c.Settings.FullTables,
+	}
+	for _, tablePattern := range tablePatterns {
+		if _, err := patterns.Compile(tablePattern); err != nil {
+			return err
+		}
+	}
+	for _, table := range c.Settings.Tables {
+		for _, filter := range table.Filters {
+			if filter.Value != "" && filter.File != "" {
+				return fmt.Errorf("table %s filter %s must have one of value or file", table.Name, filter.Name)
+			}
+		}
+		if table.KeyColumn != "" && table.BaseTable == "" {
+			return fmt.Errorf("table %s key_column requires base_table", table.Name)
+		}
+		if table.Query != "" && table.QueryFile != "" {
+			return fmt.Errorf("table %s must have one of query or query_file", table.Name)
+		}
+		if (table.Query != "" || table.QueryFile != "") && (len(table.Filters) != 0 || table.Where != "") {
+			return fmt.Errorf("table %s query can not be combined with filters and where", table.Name)
+		}
+		if table.Sample.Rows < 0 {
+			return fmt.Errorf("table %s sample rows must not be negative", table.Name)
+		}
+		if _, ok := AllowedSampleMethods[table.Sample.Method]; !ok && table.Sample.Method != "" {
+			return fmt.Errorf("no supported sample method %s", table.Sample.Method)
+		}
+	}
+	for _, tableSettings := range c.Settings.TableSettings {
+		if tableSettings.Name == "" {
+			return fmt.Errorf("table settings %+v must have name", tableSettings)
+		}
+		if _, ok := AllowedSelections[tableSettings.Selection]; !ok && tableSettings.Selection != "" {
+			return fmt.Errorf("no supported selection %s", tableSettings.Selection)
+		}
+	}
+	if (c.Settings.TenantColumn == "") != (c.Settings.Tenant == "") {
+		return fmt.Errorf("tenant_column and tenant must be set together")
+	}
+	if c.Settings.TenantColumn != "" && c.Settings.Budget.IsEnabled() {
+		return fmt.Errorf("budget picks starting rows of budget tables and can not be combined with tenant")
+	}
+	if c.Settings.Batch.IsEnabled() {
+		batch := c.Settings.Batch
+		if !strings.Contains(batch.Output, BatchIdPlaceholder) {
+			return fmt.Errorf("batch output %s must contain %s", batch.Output, BatchIdPlaceholder)
+		}
+		if (len(batch.Ids) != 0) == (batch.IdsFile != "") {
+			return fmt.Errorf("batch must have one of ids or ids_file")
+		}
+		if len(c.Settings.Tables) != 0 || c.Settings.TenantColumn != "" || c.Settings.Budget.IsEnabled() {
+			return fmt.Errorf("batch seeds are starting rows and can not be combined with tables, tenant or budget")
+		}
+		if batch.Concurrency < 0 {
+			return fmt.Errorf("batch concurrency %d must not be negative", batch.Concurrency)
+		}
+	}
+	if c.Settings.PiiSampleSize < 0 {
+		return fmt.Errorf("pii sample size %d must not be negative", c.Settings.PiiSampleSize)
+	}
+	if c.Settings.DateShift.MaxDays < 0 {
+		return fmt.Errorf("date shift max_days %d must not be negative", c.Settings.DateShift.MaxDays)
+	}
+	if c.Settings.Budget.IsEnabled() {
+		budget := c.Settings.Budget
+		if len(c.Settings.Tables) != 0 {
+			return fmt.Errorf("budget picks starting rows of budget tables and can not be combined with tables")
+		}
+		if (budget.Percent == 0) == (budget.Size == "") {
+			return fmt.Errorf("budget must have one of percent or size")
+		}
+		if budget.Percent < 0 || budget.Percent > 100 {
+			return fmt.Errorf("budget percent %v must be between 0 and 100", budget.Percent)
+		}
+		if budget.Size != "" {
+			if _, err := ParseSize(budget.Size); err != nil {
+				return err
+			}
+		}
+	}
+	for _, relation := range c.Settings.Relations {
+		if relation.Table == "" || relation.Column == "" || relation.ForeignTable == "" || relation.ForeignColumn == "" {
+			return fmt.Errorf("relation %+v must have table, column, foreign_table and foreign_column", relation)
+		}
+	}
+	for _, mask := range c.Settings.Masks {
+		if mask.Table == "" || mask.Column == "" {
+			return fmt.Errorf("mask %+v must have table and column", mask)
+		}
+		if _, ok := AllowedMaskMethods[mask.Method]; !ok {
+			return fmt.Errorf("no supported mask method %s", mask.Method)
+		}
+		if mask.Min > mask.Max {
+			return fmt.Errorf("mask %+v min must not be greater than max", mask)
+		}
+	}
+	for _, rule := range c.Settings.Rules {
+		if rule.Table == "" {
+			return fmt.Errorf("rule %+v must have table", rule)
+		}
+		if _, ok := AllowedRuleDirections[rule.Direction]; !ok {
+			return fmt.Errorf("no supported rule direction %s", rule.Direction)
+		}
+		if rule.MaxChildrenPerParent < 0 {
+			return fmt.Errorf("rule %+v max_children_per_parent must not be negative", rule)
+		}
+	}
 	return nil
 }
 
@@ -73,17 +405,36 @@ func LoadConfig(configPath string) (*Config, error) {
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	if config.Settings.Direction == "" {
+		config.Settings.Direction = constants.OUTGOING
+	}
+	if config.Settings.ExcludedReference == "" {
+		config.Settings.ExcludedReference = constants.FAIL
+	}
+	if config.Settings.DepthOverflow == "" {
+		config.Settings.DepthOverflow = constants.FOLLOW
+	}
+	if !viper.IsSet("settings.follow_one_to_one") {
+		config.Settings.FollowOneToOne = true
+	}
 	err := config.Validate()
 	if err != nil {
 		return nil, err
 	}
+	for i, table := range config.Settings.Tables {
+		if table.QueryFile == "" {
+			continue
+		}
+		query, err := os.ReadFile(table.QueryFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read query file: %w", err)
+		}
+		config.Settings.Tables[i].Query = string(query)
+	}
 
 	if config.Database.SSLMode == "" {
 		config.Database.SSLMode = "disable"
 	}
-	if config.Settings.Direction == "" {
-		config.Settings.Direction = constants.OUTGOING
-	}
 
 	return &config, nil
 }
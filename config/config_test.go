@@ -0,0 +1,146 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/t1m4/db_part_dump/internal/constants"
+)
+
+func TestParseSize(t *testing.T) {
+	type TestData struct {
+		size     string
+		expected int64
+		isErr    bool
+	}
+	tests := []TestData{
+		{size: "1024", expected: 1024},
+		{size: "5GB", expected: 5 << 30},
+		{size: "1.5 mb", expected: 3 << 19},
+		{size: "100B", expected: 100},
+		{size: "GB", isErr: true},
+		{size: "-1KB", isErr: true},
+	}
+	for _, test := range tests {
+		actual, err := ParseSize(test.size)
+		if (err != nil) != test.isErr {
+			t.Errorf("%s: wrong err %v", test.size, err)
+		}
+		if actual != test.expected {
+			t.Errorf("%s: got %d, expected %d", test.size, actual, test.expected)
+		}
+	}
+}
+
+func newValidConfig() Config {
+	return Config{
+		Database: Database{DBType: "postgres"},
+		Settings: Settings{
+			Direction:         constants.OUTGOING,
+			DepthOverflow:     constants.FOLLOW,
+			ExcludedReference: constants.FAIL,
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	type TestData struct {
+		name   string
+		modify func(c *Config)
+		isErr  bool
+	}
+	tests := []TestData{
+		{name: "test valid", modify: func(c *Config) {}},
+		{
+			name: "test budget with tables",
+			modify: func(c *Config) {
+				c.Settings.Budget = Budget{Tables: []string{"users"}, Size: "1MB"}
+				c.Settings.Tables = []Table{{Name: "orders"}}
+			},
+			isErr: true,
+		},
+		{
+			name: "test budget with tenant",
+			modify: func(c *Config) {
+				c.Settings.Budget = Budget{Tables: []string{"users"}, Size: "1MB"}
+				c.Settings.TenantColumn = "tenant_id"
+				c.Settings.Tenant = "42"
+			},
+			isErr: true,
+		},
+		{
+			name: "test batch",
+			modify: func(c *Config) {
+				c.Settings.Batch = Batch{Table: "users", Ids: []string{"1"}, Output: "user_{{id}}.sql"}
+			},
+		},
+		{
+			name: "test batch without ids",
+			modify: func(c *Config) {
+				c.Settings.Batch = Batch{Table: "users", Output: "user_{{id}}.sql"}
+			},
+			isErr: true,
+		},
+		{
+			name: "test batch with tables",
+			modify: func(c *Config) {
+				c.Settings.Batch = Batch{Table: "users", Ids: []string{"1"}, Output: "user_{{id}}.sql"}
+				c.Settings.Tables = []Table{{Name: "orders"}}
+			},
+			isErr: true,
+		},
+		{
+			name: "test batch with tenant",
+			modify: func(c *Config) {
+				c.Settings.Batch = Batch{Table: "users", Ids: []string{"1"}, Output: "user_{{id}}.sql"}
+				c.Settings.TenantColumn = "tenant_id"
+				c.Settings.Tenant = "42"
+			},
+			isErr: true,
+		},
+		{
+			name: "test batch with budget",
+			modify: func(c *Config) {
+				c.Settings.Batch = Batch{Table: "users", Ids: []string{"1"}, Output: "user_{{id}}.sql"}
+				c.Settings.Budget = Budget{Tables: []string{"users"}, Size: "1MB"}
+			},
+			isErr: true,
+		},
+	}
+	for _, test := range tests {
+		c := newValidConfig()
+		test.modify(&c)
+		err := c.Validate()
+		if (err != nil) != test.isErr {
+			t.Errorf("%s: wrong err %v", test.name, err)
+		}
+	}
+}
+
+func TestLoadConfigFollowOneToOne(t *testing.T) {
+	type TestData struct {
+		name     string
+		settings string
+		expected bool
+	}
+	tests := []TestData{
+		{name: "test default", settings: "  direction: outgoing\n", expected: true},
+		{name: "test opt-out", settings: "  follow_one_to_one: false\n", expected: false},
+	}
+	for _, test := range tests {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		content := "database:\n  db_type: postgres\nsettings:\n" + test.settings
+		err := os.WriteFile(configPath, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("wrong err: %v, expected %v", err, nil)
+		}
+		c, err := LoadConfig(configPath)
+		if err != nil {
+			t.Fatalf("%s: wrong err: %v, expected %v", test.name, err, nil)
+		}
+		if c.Settings.FollowOneToOne != test.expected {
+			t.Errorf("%s: wrong follow_one_to_one %v, expected %v", test.name, c.Settings.FollowOneToOne, test.expected)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Path of filter file to read values from stdin
+const Stdin = "-"
+
+// Read values of starting filters and batch ids from files. Stdin is used for - file
+func (c *Config) LoadFilterValues(stdin io.Reader) error {
+	for _, table := range c.Settings.Tables {
+		for i, filter := range table.Filters {
+			if filter.File == "" {
+				continue
+			}
+			values, err := readValuesFile(filter.File, filter.Name, stdin)
+			if err != nil {
+				return err
+			}
+			table.Filters[i].Values = values
+		}
+	}
+	batch := &c.Settings.Batch
+	if batch.IdsFile != "" {
+		columnName := batch.Column
+		if columnName == "" {
+			columnName = "id"
+		}
+		ids, err := readValuesFile(batch.IdsFile, columnName, stdin)
+		if err != nil {
+			return err
+		}
+		batch.Ids = ids
+	}
+	return nil
+}
+
+// Set file with values for filter of starting table.
+// Only one starting table with one filter is allowed, otherwise target of ids is ambiguous
+func (c *Config) SetIdsFrom(path string) error {
+	if len(c.Settings.Tables) != 1 || len(c.Settings.Tables[0].Filters) != 1 {
+		return fmt.Errorf("ids from %s require exactly one starting table with one filter", path)
+	}
+	filter := &c.Settings.Tables[0].Filters[0]
+	filter.Value = ""
+	filter.File = path
+	return nil
+}
+
+func readValuesFile(path string, columnName string, stdin io.Reader) ([]string, error) {
+	isCsv := strings.EqualFold(filepath.Ext(path), ".csv")
+	if path == Stdin {
+		return readValues(stdin, isCsv, columnName)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open values file: %w", err)
+	}
+	defer file.Close()
+	return readValues(file, isCsv, columnName)
+}
+
+// Read not empty values from newline-delimited or CSV reader.
+// CSV must have header, values are read from column with columnName or from the only column
+func readValues(r io.Reader, isCsv bool, columnName string) ([]string, error) {
+	values := make([]string, 0)
+	if !isCsv {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			if value := strings.TrimSpace(scanner.Text()); value != "" {
+				values = append(values, value)
+			}
+		}
+		return values, scanner.Err()
+	}
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return values, nil
+	}
+	index := slices.Index(records[0], columnName)
+	if index == -1 && len(records[0]) == 1 {
+		index = 0
+	}
+	if index == -1 {
+		return nil, fmt.Errorf("csv has no column %s", columnName)
+	}
+	for _, record := range records[1:] {
+		if value := strings.TrimSpace(record[index]); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadValues(t *testing.T) {
+	type TestData struct {
+		name     string
+		data     string
+		isCsv    bool
+		expected []string
+		isErr    bool
+	}
+	tests := []TestData{
+		{name: "test newline", data: "1\n 2 \n\n3\n", expected: []string{"1", "2", "3"}},
+		{name: "test csv column", data: "email,id\na@example.com,1\nb@example.com,2\n", isCsv: true, expected: []string{"1", "2"}},
+		{name: "test csv only column", data: "user_id\n1\n2\n", isCsv: true, expected: []string{"1", "2"}},
+		{name: "test csv without column", data: "email,user_id\na@example.com,1\n", isCsv: true, isErr: true},
+	}
+	for _, test := range tests {
+		actual, err := readValues(strings.NewReader(test.data), test.isCsv, "id")
+		if (err != nil) != test.isErr {
+			t.Errorf("%s: wrong err %v", test.name, err)
+		}
+		if diff := cmp.Diff(test.expected, actual); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", test.name, diff)
+		}
+	}
+}
+
+func TestLoadFilterValues(t *testing.T) {
+	c := &Config{Settings: Settings{Tables: []Table{
+		{Name: "users", Filters: []Filter{{Name: "id", Value: "1"}}},
+	}}}
+	err := c.SetIdsFrom(Stdin)
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+	err = c.LoadFilterValues(strings.NewReader("5\n6\n"))
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+	expected := []Filter{{Name: "id", File: Stdin, Values: []string{"5", "6"}}}
+	if diff := cmp.Diff(expected, c.Settings.Tables[0].Filters); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetIdsFrom(t *testing.T) {
+	type TestData struct {
+		name   string
+		tables []Table
+		isErr  bool
+	}
+	tests := []TestData{
+		{name: "test one filter", tables: []Table{{Name: "users", Filters: []Filter{{Name: "id", Value: "1"}}}}},
+		{name: "test without tables", isErr: true},
+		{name: "test without filters", tables: []Table{{Name: "users"}}, isErr: true},
+		{
+			name:   "test several filters",
+			tables: []Table{{Name: "users", Filters: []Filter{{Name: "id", Value: "1"}, {Name: "email", Value: "'a'"}}}},
+			isErr:  true,
+		},
+		{
+			name: "test several tables",
+			tables: []Table{
+				{Name: "users", Filters: []Filter{{Name: "id", Value: "1"}}},
+				{Name: "orders", Filters: []Filter{{Name: "id", Value: "1"}}},
+			},
+			isErr: true,
+		},
+	}
+	for _, test := range tests {
+		c := &Config{Settings: Settings{Tables: test.tables}}
+		err := c.SetIdsFrom(Stdin)
+		if (err != nil) != test.isErr {
+			t.Errorf("%s: wrong err %v", test.name, err)
+		}
+	}
+}
@@ -2,3 +2,33 @@ package constants
 
 const OUTGOING = "outgoing"
 const INCOMING = "incoming"
+const OWNED = "owned"
+const BOTH = "both"
+const NONE = "none"
+
+// ON DELETE CASCADE action of fk constraint
+const CASCADE = "c"
+
+// Handling of outgoing fks after max depth or to excluded tables
+const FOLLOW = "follow"
+const NULLIFY = "nullify"
+const FAIL = "fail"
+
+// Selection of rows to keep in table with max rows
+const RANDOM = "random"
+const NEWEST = "newest"
+const OLDEST = "oldest"
+
+// Sample methods of starting table rows. RANDOM is also supported
+const BERNOULLI = "bernoulli"
+const SYSTEM = "system"
+
+// Masking methods of column values
+const MASK_NULL = "null"
+const MASK_CONSTANT = "constant"
+const MASK_HASH = "hash"
+const MASK_EMAIL = "email"
+const MASK_NAME = "name"
+const MASK_PARTIAL = "partial"
+const MASK_RANGE = "range"
+const MASK_PSEUDONYMIZE = "pseudonymize"
@@ -6,4 +6,19 @@ type Fk struct {
 	ForeignTableName   string
 	ForeignColumnName  string
 	Direction          string
+	ConstraintName     string
+	OnDelete           string // confdeltype of constraint: a, r, c, n, d
+	IsOneToOne         bool   // Referencing column is pk or has unique constraint
+	IsNullable         bool   // Referencing column is nullable
+}
+
+type Column struct {
+	TableName    string
+	ColumnName   string
+	DataType     string
+	IsNullable   bool
+	IsPrimaryKey bool
+	IsForeignKey bool
+	IsUnique     bool
+	MaxLength    int // Max length of character varying and character columns, 0 is unlimited
 }
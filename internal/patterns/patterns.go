@@ -0,0 +1,61 @@
+package patterns
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Prefix of regex pattern. Other patterns are globs
+const RegexPrefix = "re:"
+
+// Table name patterns matched against qualified and not qualified table name
+type Patterns struct {
+	globs   []string
+	regexps []*regexp.Regexp
+}
+
+func Compile(patterns []string) (*Patterns, error) {
+	result := &Patterns{}
+	for _, pattern := range patterns {
+		if expr, ok := strings.CutPrefix(pattern, RegexPrefix); ok {
+			compiled, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("wrong regex pattern %s: %w", pattern, err)
+			}
+			result.regexps = append(result.regexps, compiled)
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("wrong glob pattern %s: %w", pattern, err)
+		}
+		result.globs = append(result.globs, pattern)
+	}
+	return result, nil
+}
+
+func (p *Patterns) IsEmpty() bool {
+	return len(p.globs) == 0 && len(p.regexps) == 0
+}
+
+// Match schema.table or table name with any pattern
+func (p *Patterns) Match(schemaName string, tableName string) bool {
+	names := []string{tableName}
+	if schemaName != "" {
+		names = append(names, fmt.Sprintf("%s.%s", schemaName, tableName))
+	}
+	for _, name := range names {
+		for _, glob := range p.globs {
+			if ok, _ := path.Match(glob, name); ok {
+				return true
+			}
+		}
+		for _, expr := range p.regexps {
+			if expr.MatchString(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package patterns_test
+
+import (
+	"testing"
+
+	"github.com/t1m4/db_part_dump/internal/patterns"
+)
+
+func TestMatch(t *testing.T) {
+	type TestData struct {
+		name      string
+		patterns  []string
+		tableName string
+		expected  bool
+	}
+	tests := []TestData{
+		{name: "test exact", patterns: []string{"django_session"}, tableName: "django_session", expected: true},
+		{name: "test glob", patterns: []string{"*_audit"}, tableName: "users_audit", expected: true},
+		{name: "test qualified glob", patterns: []string{"alpha.*_history"}, tableName: "orders_history", expected: true},
+		{name: "test other schema", patterns: []string{"beta.*"}, tableName: "orders", expected: false},
+		{name: "test regex", patterns: []string{"re:^(users|orders)$"}, tableName: "orders", expected: true},
+		{name: "test regex not matched", patterns: []string{"re:^(users|orders)$"}, tableName: "order_items", expected: false},
+		{name: "test empty", patterns: []string{}, tableName: "orders", expected: false},
+	}
+	for _, test := range tests {
+		p, err := patterns.Compile(test.patterns)
+		if err != nil {
+			t.Errorf("wrong err: %v, expected %v", err, nil)
+		}
+		actual := p.Match("alpha", test.tableName)
+		if actual != test.expected {
+			t.Errorf("%s: got %v, expected %v", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestCompileWrongPattern(t *testing.T) {
+	for _, pattern := range []string{"re:(", "[a-"} {
+		_, err := patterns.Compile([]string{pattern})
+		if err == nil {
+			t.Errorf("expected err for pattern %s", pattern)
+		}
+	}
+}
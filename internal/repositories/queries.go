@@ -6,7 +6,12 @@ SELECT
     ref_nsp.nspname AS foreign_table_schema, 
     ref_tbl.relname AS foreign_table_name,
     ref_att.attname AS foreign_column_name,
-    'outgoing' AS direction
+    'outgoing' AS direction,
+    con.conname AS constraint_name,
+    con.confdeltype::text AS on_delete,
+    -- Only incoming fks can be one-to-one extensions
+    false AS is_one_to_one,
+    NOT att.attnotnull AS is_nullable
 FROM pg_constraint con
 JOIN pg_class tbl ON con.conrelid = tbl.oid
 JOIN pg_namespace nsp ON tbl.relnamespace = nsp.oid AND nsp.nspname = '%s'
@@ -28,7 +33,12 @@ SELECT
     ref_nsp.nspname AS foreign_table_schema, 
     ref_tbl.relname AS foreign_table_name,
     ref_att.attname AS foreign_column_name,
-    'outgoing' AS direction
+    'outgoing' AS direction,
+    con.conname AS constraint_name,
+    con.confdeltype::text AS on_delete,
+    -- Only incoming fks can be one-to-one extensions
+    false AS is_one_to_one,
+    NOT att.attnotnull AS is_nullable
 FROM pg_constraint con
 JOIN pg_class tbl ON con.conrelid = tbl.oid
 JOIN pg_namespace nsp ON tbl.relnamespace = nsp.oid AND nsp.nspname = '%s'
@@ -50,7 +60,18 @@ SELECT
     nsp.nspname AS foreign_table_schema,
     tbl.relname AS foreign_table_name,
     att.attname AS foreign_column_name,
-    'incoming' AS direction
+    'incoming' AS direction,
+    con.conname AS constraint_name,
+    con.confdeltype::text AS on_delete,
+    EXISTS (
+        SELECT 1 FROM pg_index idx
+        WHERE idx.indrelid = tbl.oid
+          AND idx.indisunique
+          AND idx.indnatts = 1
+          AND idx.indkey[0] = att.attnum
+          AND idx.indpred IS NULL
+    ) AS is_one_to_one,
+    NOT att.attnotnull AS is_nullable
 FROM pg_constraint con
 JOIN pg_class ref_tbl ON con.confrelid = ref_tbl.oid
 JOIN pg_namespace ref_nsp ON ref_tbl.relnamespace = ref_nsp.oid AND ref_nsp.nspname = '%s'
@@ -80,3 +101,93 @@ WHERE
 `
 
 var Select = "SELECT %s FROM %s"
+
+var GetSchemaColumns string = `
+SELECT
+    tbl.relname AS table_name,
+    att.attname AS column_name,
+    att.atttypid::regtype::text AS data_type,
+    NOT att.attnotnull AS is_nullable,
+    EXISTS (
+        SELECT 1 FROM pg_constraint con
+        WHERE con.conrelid = tbl.oid AND con.contype = 'p' AND att.attnum = ANY(con.conkey)
+    ) AS is_primary_key,
+    EXISTS (
+        SELECT 1 FROM pg_constraint con
+        WHERE con.conrelid = tbl.oid AND con.contype = 'f' AND att.attnum = ANY(con.conkey)
+    ) AS is_foreign_key,
+    EXISTS (
+        SELECT 1 FROM pg_index idx
+        WHERE idx.indrelid = tbl.oid
+          AND idx.indisunique
+          AND idx.indnatts = 1
+          AND idx.indkey[0] = att.attnum
+          AND idx.indpred IS NULL
+    ) AS is_unique,
+    CASE
+        WHEN att.atttypid IN ('varchar'::regtype, 'bpchar'::regtype) AND att.atttypmod > 4 THEN att.atttypmod - 4
+        ELSE 0
+    END AS max_length
+FROM pg_class tbl
+JOIN pg_namespace nsp ON tbl.relnamespace = nsp.oid AND nsp.nspname = '%s'
+JOIN pg_attribute att ON att.attrelid = tbl.oid
+                     AND att.attnum > 0
+                     AND NOT att.attisdropped
+WHERE tbl.relkind IN ('r', 'p')
+ORDER BY tbl.relname, att.attnum
+`
+
+// Distinct values are sampled in random order, so values are not biased by physical order of rows
+var GetInclusionCoverage string = `
+SELECT
+    count(*) FILTER (WHERE EXISTS (SELECT 1 FROM %s t WHERE t.%s = s.value)) AS matched,
+    count(*) AS total
+FROM (
+    SELECT value FROM (SELECT DISTINCT %s AS value FROM %s WHERE %s IS NOT NULL) d
+    ORDER BY random() LIMIT %d
+) s
+`
+
+var GetTableRowEstimates string = `
+SELECT
+    tbl.relname AS table_name,
+    tbl.reltuples::bigint AS row_estimate
+FROM pg_class tbl
+JOIN pg_namespace nsp ON tbl.relnamespace = nsp.oid AND nsp.nspname = '%s'
+WHERE tbl.relkind IN ('r', 'p')
+`
+
+var SelectLimited = "SELECT %s FROM %s WHERE %s ORDER BY %s LIMIT %d"
+
+var SelectWithParents = "SELECT %s FROM %s WHERE %s"
+
+var SelectLimitedPerPartition = `
+SELECT %s FROM (
+    SELECT %s, ROW_NUMBER() OVER (PARTITION BY %s ORDER BY %s) AS row_number FROM %s%s
+) ranked
+WHERE row_number <= %d
+`
+
+// Partitions are read through their partitioned table
+var GetSchemaTables string = `
+SELECT tbl.relname AS table_name
+FROM pg_class tbl
+JOIN pg_namespace nsp ON tbl.relnamespace = nsp.oid AND nsp.nspname = '%s'
+WHERE tbl.relkind IN ('r', 'p') AND NOT tbl.relispartition
+ORDER BY tbl.relname
+`
+
+// Text form of row is close to COPY output, unlike on-disk tuple size
+var SelectRowsSize = "SELECT COALESCE(sum(octet_length(t::text)), 0)::bigint FROM %s t%s"
+
+var SelectRowsCount = "SELECT count(*) FROM %s%s"
+
+var SelectSample = "SELECT %s FROM %s%s%s ORDER BY %s LIMIT %d"
+
+var SelectFilteredReferencesCount = `
+SELECT count(*) FROM %s c
+WHERE %s AND c.%s IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM %s p WHERE p.%s = c.%s AND (%s))
+`
+
+var SelectRowsSample = "SELECT * FROM %s%s LIMIT %d"
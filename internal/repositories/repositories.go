@@ -25,21 +25,80 @@ type RepositoriesI interface {
 		tableName string,
 		isIncludeIncoming bool,
 	) ([]db.Fk, error)
-	GetFkIdRows(ctx context.Context, schemaName string, table config.Table, fks []db.Fk) ([]map[string]any, error)
+	GetFkIdRows(ctx context.Context, schemaName string, table config.Table, fks []db.Fk, columnNames ...string) ([]map[string]any, error)
 	GetRows(
 		ctx context.Context,
 		schemaName string,
 		pkTable *schemas.Table,
 		writer *bufio.Writer,
+		transforms ...RowTransform,
 	) error
+	GetColumnValues(ctx context.Context, schemaName string, pkTable *schemas.Table, columnName string) ([]map[string]any, error)
+	GetTableRowEstimates(ctx context.Context, schemaName string) (map[string]int64, error)
+	GetLimitedPkIdRows(
+		ctx context.Context,
+		schemaName string,
+		pkTable *schemas.Table,
+		pkColumnName string,
+		orderBy string,
+		limit int,
+	) ([]map[string]any, error)
+	GetQueryPkIdRows(ctx context.Context, query string, pkColumnName string) ([]map[string]any, error)
+	GetSamplePkIdRows(
+		ctx context.Context,
+		schemaName string,
+		table config.Table,
+		pkColumnName string,
+		tableSample string,
+		orderBy string,
+		limit int,
+	) ([]map[string]any, error)
+	GetLimitedPerParentPkIdRows(
+		ctx context.Context,
+		schemaName string,
+		table config.Table,
+		pkColumnName string,
+		parentColumnName string,
+		orderBy string,
+		limit int,
+	) ([]map[string]any, error)
+	GetPkIdRowsWithParents(
+		ctx context.Context,
+		schemaName string,
+		pkTable *schemas.Table,
+		pkColumnName string,
+		fks []db.Fk,
+		parents map[string]*schemas.Table,
+	) ([]map[string]any, error)
+	GetColumns(ctx context.Context, schemaName string) ([]db.Column, error)
+	GetSchemaSize(ctx context.Context, schemaName string) (int64, error)
+	GetFilteredReferencesCount(ctx context.Context, schemaName string, pkTable *schemas.Table, fk db.Fk) (int64, error)
+	GetRowsSize(ctx context.Context, schemaName string, pkTable *schemas.Table) (int64, error)
+	GetRowsCount(ctx context.Context, schemaName string, pkTable *schemas.Table) (int64, error)
+	GetRowsSample(ctx context.Context, schemaName string, pkTable *schemas.Table, limit int) ([]map[string]any, error)
+	GetInclusionCoverage(
+		ctx context.Context,
+		schemaName string,
+		relation config.Relation,
+		sampleSize int,
+	) (int, int, error)
 }
 
+// Modify row values before encoding. Return false to skip row
+type RowTransform func(columns []db.Column, values []any) (bool, error)
+
 type Repositories struct {
-	db *sql.DB
+	db             *sql.DB
+	defaultFilters map[string]string
 }
 
 func New(db *sql.DB) *Repositories {
-	return &Repositories{db: db}
+	return &Repositories{db: db, defaultFilters: make(map[string]string)}
+}
+
+// Set predicates by table name which are combined with every query of table rows
+func (r *Repositories) SetDefaultFilters(defaultFilters map[string]string) {
+	r.defaultFilters = defaultFilters
 }
 
 func (r *Repositories) GetPKColumnName(ctx context.Context, schemaName string, tableName string) (string, error) {
@@ -54,12 +113,12 @@ func (r *Repositories) GetPKColumnName(ctx context.Context, schemaName string, t
 }
 
 func (r *Repositories) GetPkIdRows(ctx context.Context, schemaName string, table config.Table, pkColumnName string) ([]map[string]any, error) {
-	condition := buildFilterCondition(table)
+	condition, args := r.buildFilterCondition(table)
 	query := fmt.Sprintf(Select, pkColumnName, buildTableNameWithSchema(schemaName, table.Name))
 	query += condition
 	slog.Debug("SQL", "GetPkIds", query)
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -93,7 +152,17 @@ func (r *Repositories) GetFKs(
 	fks := make([]db.Fk, 0)
 	for rows.Next() {
 		fk := db.Fk{}
-		if err := rows.Scan(&fk.ColumnName, &fk.ForeignTableSchema, &fk.ForeignTableName, &fk.ForeignColumnName, &fk.Direction); err != nil {
+		if err := rows.Scan(
+			&fk.ColumnName,
+			&fk.ForeignTableSchema,
+			&fk.ForeignTableName,
+			&fk.ForeignColumnName,
+			&fk.Direction,
+			&fk.ConstraintName,
+			&fk.OnDelete,
+			&fk.IsOneToOne,
+			&fk.IsNullable,
+		); err != nil {
 			return nil, err
 		}
 		fks = append(fks, fk)
@@ -101,15 +170,22 @@ func (r *Repositories) GetFKs(
 	return fks, nil
 }
 
-func (r *Repositories) GetFkIdRows(ctx context.Context, schemaName string, table config.Table, fks []db.Fk) ([]map[string]any, error) {
-	fkColumnNames := getFkColumnNames(fks)
+// Get fk column values of table rows matching filters. Column names are selected too
+func (r *Repositories) GetFkIdRows(
+	ctx context.Context,
+	schemaName string,
+	table config.Table,
+	fks []db.Fk,
+	columnNames ...string,
+) ([]map[string]any, error) {
+	fkColumnNames := getFkColumnNames(fks, columnNames)
 	fkColumnNamesString := strings.Join(fkColumnNames, ", ")
-	condition := buildFilterCondition(table)
+	condition, args := r.buildFilterCondition(table)
 	query := fmt.Sprintf(Select, fkColumnNamesString, buildTableNameWithSchema(schemaName, table.Name))
 	query += condition
 	slog.Debug("SQL", "GetFkIds", query)
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -126,15 +202,16 @@ func (r *Repositories) GetRows(
 	schemaName string,
 	pkTable *schemas.Table,
 	writer *bufio.Writer,
+	transforms ...RowTransform,
 ) error {
 	// TODO add ordering
 	tableName := buildTableNameWithSchema(schemaName, pkTable.Name)
 	query := fmt.Sprintf(Select, "*", tableName)
-	condition := buildPkCondition(pkTable)
+	condition, args := r.buildPkCondition(pkTable, nil)
 	query += condition
 	slog.Debug("SQL", "GetRows", query)
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -146,6 +223,10 @@ func (r *Repositories) GetRows(
 	if err != nil {
 		return err
 	}
+	rowColumns, err := getRowColumns(rows, pkTable.Name)
+	if err != nil {
+		return err
+	}
 
 	fileColumns := strings.Join(columns, ", ")
 	slog.Debug("FILE", "Columns", fileColumns)
@@ -165,6 +246,13 @@ func (r *Repositories) GetRows(
 		if err = rows.Scan(valuePtrs...); err != nil {
 			return err
 		}
+		isKept, err := applyTransforms(transforms, rowColumns, values)
+		if err != nil {
+			return err
+		}
+		if !isKept {
+			continue
+		}
 		var rowBuf strings.Builder
 		for i := range columns {
 			value := values[i]
@@ -192,3 +280,369 @@ func (r *Repositories) GetRows(
 	}
 	return nil
 }
+
+// Get column values of dump table rows
+func (r *Repositories) GetColumnValues(
+	ctx context.Context,
+	schemaName string,
+	pkTable *schemas.Table,
+	columnName string,
+) ([]map[string]any, error) {
+	query := fmt.Sprintf(Select, columnName, buildTableNameWithSchema(schemaName, pkTable.Name))
+	condition, args := r.buildPkCondition(pkTable, nil)
+	query += condition
+	slog.Debug("SQL", "GetColumnValues", query)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return getManyRows(rows, []string{columnName})
+}
+
+// Get all columns of schema tables with key and nullability info
+func (r *Repositories) GetColumns(ctx context.Context, schemaName string) ([]db.Column, error) {
+	query := fmt.Sprintf(GetSchemaColumns, schemaName)
+	slog.Debug("SQL", "GetSchemaColumns", query)
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns := make([]db.Column, 0)
+	for rows.Next() {
+		column := db.Column{}
+		if err := rows.Scan(
+			&column.TableName,
+			&column.ColumnName,
+			&column.DataType,
+			&column.IsNullable,
+			&column.IsPrimaryKey,
+			&column.IsForeignKey,
+			&column.IsUnique,
+			&column.MaxLength,
+		); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// Count how many of randomly sampled distinct column values exist in foreign column.
+// Return matched and total sampled values count
+func (r *Repositories) GetInclusionCoverage(
+	ctx context.Context,
+	schemaName string,
+	relation config.Relation,
+	sampleSize int,
+) (int, int, error) {
+	query := fmt.Sprintf(
+		GetInclusionCoverage,
+		buildTableNameWithSchema(schemaName, relation.ForeignTable),
+		relation.ForeignColumn,
+		relation.Column,
+		buildTableNameWithSchema(schemaName, relation.Table),
+		relation.Column,
+		sampleSize,
+	)
+	slog.Debug("SQL", "GetInclusionCoverage", query)
+	var matched, total int
+	err := r.db.QueryRowContext(ctx, query).Scan(&matched, &total)
+	if err != nil {
+		return 0, 0, err
+	}
+	return matched, total, nil
+}
+
+// Get estimated rows count of schema tables from planner statistics. Not analyzed tables have -1
+func (r *Repositories) GetTableRowEstimates(ctx context.Context, schemaName string) (map[string]int64, error) {
+	query := fmt.Sprintf(GetTableRowEstimates, schemaName)
+	slog.Debug("SQL", "GetTableRowEstimates", query)
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	estimates := make(map[string]int64)
+	for rows.Next() {
+		var tableName string
+		var estimate int64
+		if err := rows.Scan(&tableName, &estimate); err != nil {
+			return nil, err
+		}
+		estimates[tableName] = estimate
+	}
+	return estimates, nil
+}
+
+// Get count of dump rows of table which fk references row removed by default filter of foreign table
+func (r *Repositories) GetFilteredReferencesCount(
+	ctx context.Context,
+	schemaName string,
+	pkTable *schemas.Table,
+	fk db.Fk,
+) (int64, error) {
+	defaultFilter, ok := r.defaultFilters[fk.ForeignTableName]
+	if !ok {
+		return 0, nil
+	}
+	expression, args := r.buildPkConditionExpression(pkTable, nil)
+	query := fmt.Sprintf(
+		SelectFilteredReferencesCount,
+		buildTableNameWithSchema(schemaName, pkTable.Name),
+		expression,
+		fk.ColumnName,
+		buildTableNameWithSchema(schemaName, fk.ForeignTableName),
+		fk.ForeignColumnName,
+		fk.ColumnName,
+		defaultFilter,
+	)
+	slog.Debug("SQL", "GetFilteredReferencesCount", query)
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Get size of all rows of tables in schema in bytes.
+// Size is text width of rows like GetRowsSize, so it can be compared with dump size
+func (r *Repositories) GetSchemaSize(ctx context.Context, schemaName string) (int64, error) {
+	query := fmt.Sprintf(GetSchemaTables, schemaName)
+	slog.Debug("SQL", "GetSchemaTables", query)
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	tableNames := make([]string, 0)
+	for rows.Next() {
+		var tableName string
+		if err := rows.Scan(&tableName); err != nil {
+			return 0, err
+		}
+		tableNames = append(tableNames, tableName)
+	}
+
+	var schemaSize int64
+	for _, tableName := range tableNames {
+		query := fmt.Sprintf(SelectRowsSize, buildTableNameWithSchema(schemaName, tableName), "")
+		slog.Debug("SQL", "GetSchemaSize", query)
+		var size int64
+		err := r.db.QueryRowContext(ctx, query).Scan(&size)
+		if err != nil {
+			return 0, err
+		}
+		schemaSize += size
+	}
+	return schemaSize, nil
+}
+
+// Get size of dump rows of table in bytes
+func (r *Repositories) GetRowsSize(ctx context.Context, schemaName string, pkTable *schemas.Table) (int64, error) {
+	condition, args := r.buildPkCondition(pkTable, nil)
+	query := fmt.Sprintf(SelectRowsSize, buildTableNameWithSchema(schemaName, pkTable.Name), condition)
+	slog.Debug("SQL", "GetRowsSize", query)
+
+	var size int64
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&size)
+	if err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// Get count of dump rows of table
+func (r *Repositories) GetRowsCount(ctx context.Context, schemaName string, pkTable *schemas.Table) (int64, error) {
+	condition, args := r.buildPkCondition(pkTable, nil)
+	query := fmt.Sprintf(SelectRowsCount, buildTableNameWithSchema(schemaName, pkTable.Name), condition)
+	slog.Debug("SQL", "GetRowsCount", query)
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Get first limit dump rows of table with all columns
+func (r *Repositories) GetRowsSample(
+	ctx context.Context,
+	schemaName string,
+	pkTable *schemas.Table,
+	limit int,
+) ([]map[string]any, error) {
+	condition, args := r.buildPkCondition(pkTable, nil)
+	query := fmt.Sprintf(SelectRowsSample, buildTableNameWithSchema(schemaName, pkTable.Name), condition, limit)
+	slog.Debug("SQL", "GetRowsSample", query)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	return getManyRows(rows, columnNames)
+}
+
+// Get pk ids of first limit dump rows of table ordered by orderBy expression
+func (r *Repositories) GetLimitedPkIdRows(
+	ctx context.Context,
+	schemaName string,
+	pkTable *schemas.Table,
+	pkColumnName string,
+	orderBy string,
+	limit int,
+) ([]map[string]any, error) {
+	expression, args := r.buildPkConditionExpression(pkTable, nil)
+	query := fmt.Sprintf(
+		SelectLimited,
+		pkColumnName,
+		buildTableNameWithSchema(schemaName, pkTable.Name),
+		expression,
+		orderBy,
+		limit,
+	)
+	slog.Debug("SQL", "GetLimitedPkIdRows", query)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return getManyRows(rows, []string{pkColumnName})
+}
+
+// Get pk ids returned by user query in read-only transaction.
+// Query must return only pk column
+func (r *Repositories) GetQueryPkIdRows(ctx context.Context, query string, pkColumnName string) ([]map[string]any, error) {
+	slog.Debug("SQL", "GetQueryPkIdRows", query)
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if len(columnNames) != 1 || columnNames[0] != pkColumnName {
+		return nil, fmt.Errorf("query must return only pk column %s, got %v", pkColumnName, columnNames)
+	}
+	return getManyRows(rows, columnNames)
+}
+
+// Get pk ids of first limit table rows matching filters ordered by orderBy expression.
+// Table sample clause is applied before filters
+func (r *Repositories) GetSamplePkIdRows(
+	ctx context.Context,
+	schemaName string,
+	table config.Table,
+	pkColumnName string,
+	tableSample string,
+	orderBy string,
+	limit int,
+) ([]map[string]any, error) {
+	condition, args := r.buildFilterCondition(table)
+	query := fmt.Sprintf(
+		SelectSample,
+		pkColumnName,
+		buildTableNameWithSchema(schemaName, table.Name),
+		tableSample,
+		condition,
+		orderBy,
+		limit,
+	)
+	slog.Debug("SQL", "GetSamplePkIdRows", query)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return getManyRows(rows, []string{pkColumnName})
+}
+
+// Get pk ids of first limit table rows per parent column value ordered by orderBy expression
+func (r *Repositories) GetLimitedPerParentPkIdRows(
+	ctx context.Context,
+	schemaName string,
+	table config.Table,
+	pkColumnName string,
+	parentColumnName string,
+	orderBy string,
+	limit int,
+) ([]map[string]any, error) {
+	condition, args := r.buildFilterCondition(table)
+	query := fmt.Sprintf(
+		SelectLimitedPerPartition,
+		pkColumnName,
+		pkColumnName,
+		parentColumnName,
+		orderBy,
+		buildTableNameWithSchema(schemaName, table.Name),
+		condition,
+		limit,
+	)
+	slog.Debug("SQL", "GetLimitedPerParentPkIdRows", query)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return getManyRows(rows, []string{pkColumnName})
+}
+
+// Get pk ids of dump rows of table which fks are NULL or reference dump rows of parent tables
+func (r *Repositories) GetPkIdRowsWithParents(
+	ctx context.Context,
+	schemaName string,
+	pkTable *schemas.Table,
+	pkColumnName string,
+	fks []db.Fk,
+	parents map[string]*schemas.Table,
+) ([]map[string]any, error) {
+	expression, args := r.buildPkConditionExpression(pkTable, nil)
+	conditions := []string{expression}
+	for _, fk := range fks {
+		parent := parents[fk.ForeignTableName]
+		expression, args = r.buildPkConditionExpression(parent, args)
+		conditions = append(conditions, fmt.Sprintf(
+			"(%s IS NULL OR %s IN (SELECT %s FROM %s WHERE %s))",
+			fk.ColumnName,
+			fk.ColumnName,
+			fk.ForeignColumnName,
+			buildTableNameWithSchema(schemaName, parent.Name),
+			expression,
+		))
+	}
+	query := fmt.Sprintf(
+		SelectWithParents,
+		pkColumnName,
+		buildTableNameWithSchema(schemaName, pkTable.Name),
+		strings.Join(conditions, " AND "),
+	)
+	slog.Debug("SQL", "GetPkIdRowsWithParents", query)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return getManyRows(rows, []string{pkColumnName})
+}
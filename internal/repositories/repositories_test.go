@@ -81,6 +81,42 @@ func TestGetPks(t *testing.T) {
 			},
 			nil,
 		},
+		{
+			"test where",
+			"alpha",
+			config.Table{
+				Name:    "users",
+				Filters: []config.Filter{{Name: "id", Value: "1, 2, 3"}},
+				Where:   "status = 'active'",
+			},
+			"id",
+			[]map[string]any{
+				{"id": int64(1)},
+				{"id": int64(2)},
+			},
+			nil,
+		},
+		{
+			"test values",
+			"alpha",
+			config.Table{Name: "users", Filters: []config.Filter{{Name: "id", Values: []string{"1", "3"}}}},
+			"id",
+			[]map[string]any{
+				{"id": int64(1)},
+				{"id": int64(3)},
+			},
+			nil,
+		},
+		{
+			"test guid values",
+			"alpha",
+			config.Table{Name: "table_one", Filters: []config.Filter{{Name: "id", Values: []string{"11111111-1111-1111-1111-111111111111"}}}},
+			"id",
+			[]map[string]any{
+				{"id": []byte("11111111-1111-1111-1111-111111111111")},
+			},
+			nil,
+		},
 		{
 			"test empty",
 			"alpha",
@@ -129,6 +165,8 @@ func TestGetFks(t *testing.T) {
 					ForeignTableName:   "users",
 					ForeignColumnName:  "id",
 					Direction:          constants.OUTGOING,
+					ConstraintName:     "orders_user_id_fkey",
+					OnDelete:           constants.CASCADE,
 				},
 			},
 			err: nil,
@@ -146,18 +184,27 @@ func TestGetFks(t *testing.T) {
 					ForeignTableName:   "users",
 					ForeignColumnName:  "id",
 					Direction:          constants.OUTGOING,
+					ConstraintName:     "orders_user_id_fkey",
+					OnDelete:           constants.CASCADE,
 				},
 				{
 					ColumnName:         "id",
 					ForeignTableSchema: "alpha",
 					ForeignTableName:   "order_items",
-					ForeignColumnName:  "order_id", Direction: constants.INCOMING},
+					ForeignColumnName:  "order_id",
+					Direction:          constants.INCOMING,
+					ConstraintName:     "order_items_order_id_fkey",
+					OnDelete:           constants.CASCADE,
+				},
 				{
 					ColumnName:         "id",
 					ForeignTableSchema: "alpha",
 					ForeignTableName:   "user_payment_methods",
 					ForeignColumnName:  "order_id",
 					Direction:          constants.INCOMING,
+					ConstraintName:     "user_payment_methods_order_id_fkey",
+					OnDelete:           "n",
+					IsNullable:         true,
 				},
 				{
 					ColumnName:         "id",
@@ -165,6 +212,8 @@ func TestGetFks(t *testing.T) {
 					ForeignTableName:   "order_coupons",
 					ForeignColumnName:  "order_id",
 					Direction:          constants.INCOMING,
+					ConstraintName:     "order_coupons_order_id_fkey",
+					OnDelete:           constants.CASCADE,
 				},
 			},
 			err: nil,
@@ -270,3 +319,124 @@ ALTER TABLE alpha.user_payment_methods ENABLE TRIGGER ALL;
 		}
 	}
 }
+
+func TestGetColumns(t *testing.T) {
+	testDb := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, testDb)
+	repos := repositories.New(testDb)
+	ctx := context.Background()
+	tableName := "user_payment_methods"
+	expected := []db.Column{
+		{TableName: tableName, ColumnName: "id", DataType: "integer", IsPrimaryKey: true, IsUnique: true},
+		{TableName: tableName, ColumnName: "user_id", DataType: "integer", IsForeignKey: true},
+		{TableName: tableName, ColumnName: "order_id", DataType: "integer", IsNullable: true, IsForeignKey: true},
+		{TableName: tableName, ColumnName: "payment_type", DataType: "character varying"},
+		{TableName: tableName, ColumnName: "card_number", DataType: "character varying", IsNullable: true},
+		{TableName: tableName, ColumnName: "expiry_date", DataType: "date", IsNullable: true},
+		{TableName: tableName, ColumnName: "is_default", DataType: "boolean", IsNullable: true},
+		{TableName: tableName, ColumnName: "created_at", DataType: "timestamp without time zone", IsNullable: true},
+	}
+	columns, err := repos.GetColumns(ctx, "alpha")
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+	actual := make([]db.Column, 0)
+	for _, column := range columns {
+		if column.TableName == tableName {
+			actual = append(actual, column)
+		}
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetInclusionCoverage(t *testing.T) {
+	type TestData struct {
+		name            string
+		relation        config.Relation
+		sampleSize      int
+		expectedMatched int
+		expectedTotal   int
+	}
+	testDb := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, testDb)
+	repos := repositories.New(testDb)
+	ctx := context.Background()
+	tests := []TestData{
+		{
+			name:            "test all values found",
+			relation:        config.Relation{Table: "orders", Column: "user_id", ForeignTable: "users", ForeignColumn: "id"},
+			expectedMatched: 4,
+			expectedTotal:   4,
+		},
+		{
+			name:            "test sample size",
+			relation:        config.Relation{Table: "order_items", Column: "order_id", ForeignTable: "orders", ForeignColumn: "id"},
+			sampleSize:      3,
+			expectedMatched: 3,
+			expectedTotal:   3,
+		},
+		{
+			name:            "test no values found",
+			relation:        config.Relation{Table: "order_items", Column: "product_id", ForeignTable: "orders", ForeignColumn: "id"},
+			expectedMatched: 0,
+			expectedTotal:   8,
+		},
+	}
+	for _, test := range tests {
+		sampleSize := test.sampleSize
+		if sampleSize == 0 {
+			sampleSize = 100
+		}
+		matched, total, err := repos.GetInclusionCoverage(ctx, "alpha", test.relation, sampleSize)
+		if err != nil {
+			t.Errorf("wrong err: %v, expected %v", err, nil)
+		}
+		if matched != test.expectedMatched || total != test.expectedTotal {
+			t.Errorf("wrong coverage %d/%d, expected %d/%d", matched, total, test.expectedMatched, test.expectedTotal)
+		}
+	}
+}
+
+func TestGetQueryPkIdRows(t *testing.T) {
+	type TestData struct {
+		name     string
+		query    string
+		expected []map[string]any
+		isErr    bool
+	}
+	testDb := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, testDb)
+	repos := repositories.New(testDb)
+	ctx := context.Background()
+	tests := []TestData{
+		{
+			name: "test join query",
+			query: `SELECT u.id FROM alpha.users u
+				JOIN alpha.orders o ON o.user_id = u.id
+				JOIN alpha.order_coupons oc ON oc.order_id = o.id
+				JOIN alpha.coupons c ON c.id = oc.coupon_id AND c.code = 'SUMMER15'`,
+			expected: []map[string]any{{"id": int64(2)}},
+		},
+		{
+			name:  "test not pk column",
+			query: "SELECT username FROM alpha.users",
+			isErr: true,
+		},
+		{
+			name:  "test read-only transaction",
+			query: "DELETE FROM alpha.order_coupons RETURNING order_id AS id",
+			isErr: true,
+		},
+	}
+	for _, test := range tests {
+		actual, err := repos.GetQueryPkIdRows(ctx, test.query, "id")
+		if (err != nil) != test.isErr {
+			t.Errorf("%s: wrong err %v", test.name, err)
+		}
+		if diff := cmp.Diff(test.expected, actual); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", test.name, diff)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/t1m4/db_part_dump/internal/repositories"
+	"github.com/t1m4/db_part_dump/internal/schemas"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -29,3 +30,26 @@ func TestAnyToPsqlString(t *testing.T) {
 		}
 	}
 }
+
+func TestPksToValues(t *testing.T) {
+	type TestData struct {
+		name     string
+		pks      schemas.Pks
+		expected []string
+	}
+	tests := []TestData{
+		{name: "test int ids", pks: schemas.Pks{"2": true, "1": true}, expected: []string{"1", "2"}},
+		{
+			name:     "test text ids",
+			pks:      schemas.Pks{"'b'": true, "'a'": true},
+			expected: []string{"a", "b"},
+		},
+		{name: "test empty", pks: schemas.Pks{}, expected: []string{}},
+	}
+	for _, test := range tests {
+		actual := repositories.PksToValues(test.pks)
+		if diff := cmp.Diff(test.expected, actual); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", test.name, diff)
+		}
+	}
+}
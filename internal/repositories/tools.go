@@ -3,6 +3,7 @@ package repositories
 import (
 	"database/sql"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -10,31 +11,46 @@ import (
 	"github.com/t1m4/db_part_dump/config"
 	"github.com/t1m4/db_part_dump/internal/db"
 	"github.com/t1m4/db_part_dump/internal/schemas"
+
+	"github.com/lib/pq"
 )
 
-// Get unique column names
-func getFkColumnNames(fks []db.Fk) []string {
+// Get unique column names of fks and other columns
+func getFkColumnNames(fks []db.Fk, columnNames []string) []string {
 	namesSet := make(map[string]bool, 0)
 	fkColumnNames := make([]string, 0)
 	for _, fk := range fks {
 		namesSet[fk.ColumnName] = true
 	}
+	for _, columnName := range columnNames {
+		namesSet[columnName] = true
+	}
 	for fkName := range namesSet {
 		fkColumnNames = append(fkColumnNames, fkName)
 	}
 	return fkColumnNames
 }
 
-// Build condition based on tabl filters
-func buildFilterCondition(table config.Table) string {
-	var condition strings.Builder
-	if len(table.Filters) != 0 {
-		condition.WriteString(" where ")
-	}
+// Build condition based on table filters and where predicate.
+// Filter values loaded from file are sent as array parameter, its type is inferred from column
+func buildFilterCondition(table config.Table) (string, []any) {
+	conditions := make([]string, 0, len(table.Filters)+1)
+	args := make([]any, 0)
 	for _, filter := range table.Filters {
-		condition.WriteString(fmt.Sprintf("%s in (%s)", filter.Name, filter.Value))
+		if filter.Values != nil {
+			args = append(args, pq.Array(filter.Values))
+			conditions = append(conditions, fmt.Sprintf("%s = ANY($%d)", filter.Name, len(args)))
+			continue
+		}
+		conditions = append(conditions, fmt.Sprintf("%s in (%s)", filter.Name, filter.Value))
+	}
+	if table.Where != "" {
+		conditions = append(conditions, fmt.Sprintf("(%s)", table.Where))
 	}
-	return condition.String()
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return fmt.Sprintf(" where %s", strings.Join(conditions, " and ")), args
 }
 
 func buildTableNameWithSchema(schemaName string, tableName string) string {
@@ -65,19 +81,47 @@ func getManyRows(rows *sql.Rows, columnNames []string) ([]map[string]any, error)
 	return resultRows, nil
 }
 
-func buildPkCondition(pkTable *schemas.Table) string {
-	conditions := make([]string, 0)
-	for name, pks := range pkTable.Filters {
-		i := 0
-		filterPks := make([]string, len(pks))
-		for pk := range pks {
-			filterPks[i] = pk
-			i++
-		}
-		conditions = append(conditions, fmt.Sprintf("%s in (%s)", name, strings.Join(filterPks, ", ")))
+func buildPkCondition(pkTable *schemas.Table, args []any) (string, []any) {
+	if pkTable.IsFull {
+		return "", args
+	}
+	expression, args := buildPkConditionExpression(pkTable, args)
+	return fmt.Sprintf(" WHERE %s", expression), args
+}
+
+// Build expression matching dump rows of table.
+// Ids are appended to args as array parameters, so large id sets are not inlined into query
+func buildPkConditionExpression(pkTable *schemas.Table, args []any) (string, []any) {
+	if pkTable.IsFull {
+		return "true", args
+	}
+	names := make([]string, 0, len(pkTable.Filters))
+	for name := range pkTable.Filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	conditions := make([]string, 0, len(names))
+	for _, name := range names {
+		args = append(args, pq.Array(PksToValues(pkTable.Filters[name])))
+		conditions = append(conditions, fmt.Sprintf("%s = ANY($%d)", name, len(args)))
 	}
-	return fmt.Sprintf(" WHERE %s", strings.Join(conditions, " OR "))
+	if len(conditions) == 0 {
+		return "false", args
+	}
+	return fmt.Sprintf("(%s)", strings.Join(conditions, " OR ")), args
+}
 
+// Convert ids to sorted values of array parameter. Text ids are stored as quoted SQL literals
+func PksToValues(pks schemas.Pks) []string {
+	values := make([]string, 0, len(pks))
+	for pk := range pks {
+		if len(pk) >= 2 && strings.HasPrefix(pk, "'") && strings.HasSuffix(pk, "'") {
+			pk = pk[1 : len(pk)-1]
+		}
+		values = append(values, pk)
+	}
+	sort.Strings(values)
+	return values
 }
 
 // TODO check is it complete
@@ -102,3 +146,61 @@ func AnyToPsqlString(value any) string {
 		return fmt.Sprintf("%v", v)
 	}
 }
+
+// Get columns info of result rows
+func getRowColumns(rows *sql.Rows, tableName string) ([]db.Column, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]db.Column, len(columnTypes))
+	for i, columnType := range columnTypes {
+		columns[i] = db.Column{
+			TableName:  tableName,
+			ColumnName: columnType.Name(),
+			DataType:   strings.ToLower(columnType.DatabaseTypeName()),
+		}
+	}
+	return columns, nil
+}
+
+// Apply transforms one by one until one of them skips row
+func applyTransforms(transforms []RowTransform, columns []db.Column, values []any) (bool, error) {
+	for _, transform := range transforms {
+		isKept, err := transform(columns, values)
+		if err != nil || !isKept {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Build condition based on table filters, where predicate and default filter of table
+func (r *Repositories) buildFilterCondition(table config.Table) (string, []any) {
+	if defaultFilter, ok := r.defaultFilters[table.Name]; ok {
+		if table.Where == "" {
+			table.Where = defaultFilter
+		} else {
+			table.Where = fmt.Sprintf("(%s) and (%s)", table.Where, defaultFilter)
+		}
+	}
+	return buildFilterCondition(table)
+}
+
+// Build condition matching dump rows of table which pass default filter of table
+func (r *Repositories) buildPkCondition(pkTable *schemas.Table, args []any) (string, []any) {
+	if _, ok := r.defaultFilters[pkTable.Name]; !ok {
+		return buildPkCondition(pkTable, args)
+	}
+	expression, args := r.buildPkConditionExpression(pkTable, args)
+	return fmt.Sprintf(" WHERE %s", expression), args
+}
+
+// Build expression matching dump rows of table which pass default filter of table
+func (r *Repositories) buildPkConditionExpression(pkTable *schemas.Table, args []any) (string, []any) {
+	expression, args := buildPkConditionExpression(pkTable, args)
+	if defaultFilter, ok := r.defaultFilters[pkTable.Name]; ok {
+		return fmt.Sprintf("%s AND (%s)", expression, defaultFilter), args
+	}
+	return expression, args
+}
@@ -8,8 +8,23 @@ type Filter struct {
 }
 type Pks map[string]bool
 
+// Days of date shift by pk value in psql format
+type DateShifts map[string]int
+
+// Nullable fk which is not followed.
+// Column value is set to NULL when referenced row is not in dump
+type NullFk struct {
+	ForeignTableName  string
+	ForeignColumnName string
+}
+
 type Table struct {
 	Name    string
 	Filters map[string]Pks
 	Fks     map[string]*Table
+	NullFks map[string]NullFk // Column name to not followed fk
+	IsFull  bool              // All table rows are dumped
+	// Date shift of rows by pk value. Rows without shift are not changed
+	ShiftColumn string
+	DateShifts  DateShifts
 }
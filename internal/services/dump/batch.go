@@ -0,0 +1,122 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/t1m4/db_part_dump/config"
+)
+
+// Count of seeds dumped at the same time by default
+const defaultBatchConcurrency = 4
+
+// Build output path of seed id. Path separators in id are replaced
+func buildBatchOutput(output string, id string) string {
+	id = strings.NewReplacer("/", "_", "\\", "_").Replace(id)
+	return strings.ReplaceAll(output, config.BatchIdPlaceholder, id)
+}
+
+// Build output paths of seed ids. Seeds which share output path are error
+func buildBatchOutputs(output string, ids []string) ([]string, error) {
+	outputs := make([]string, len(ids))
+	idsByOutput := make(map[string]string, len(ids))
+	for i, id := range ids {
+		outputs[i] = buildBatchOutput(output, id)
+		if otherId, ok := idsByOutput[outputs[i]]; ok {
+			return nil, fmt.Errorf("batch seeds %s and %s have same output %s", otherId, id, outputs[i])
+		}
+		idsByOutput[outputs[i]] = id
+	}
+	return outputs, nil
+}
+
+// Dump every seed id of batch table to own file. Seeds are dumped concurrently
+// and share catalog caches and connection pool
+func (d *DumpService) startBatchDump(ctx context.Context) error {
+	batch := d.c.Settings.Batch
+	// Ids file is read after config validation
+	if len(batch.Ids) == 0 {
+		return fmt.Errorf("batch has no seed ids")
+	}
+	outputs, err := buildBatchOutputs(batch.Output, batch.Ids)
+	if err != nil {
+		return err
+	}
+	// Shared checks fail once before any seed is dumped
+	err = d.exporter.CheckSecret()
+	if err != nil {
+		return err
+	}
+	err = d.init(ctx)
+	if err != nil {
+		return err
+	}
+	columnName := batch.Column
+	if columnName == "" {
+		pkColumnName, err := d.getPkColumnName(ctx, batch.Table)
+		if err != nil {
+			return err
+		}
+		columnName = pkColumnName
+	}
+	concurrency := batch.Concurrency
+	if concurrency == 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var batchErr error
+	semaphore := make(chan struct{}, concurrency)
+	for i, id := range batch.Ids {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			err := d.dumpSeed(ctx, columnName, id, outputs[i])
+			if err != nil {
+				errOnce.Do(func() {
+					batchErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	return batchErr
+}
+
+// Dump rows reached from one seed id to its output file. File is created only when dump of seed succeeds
+func (d *DumpService) dumpSeed(ctx context.Context, columnName string, id string, output string) error {
+	tables := []config.Table{{
+		Name:    d.c.Settings.Batch.Table,
+		Filters: []config.Filter{{Name: columnName, Values: []string{id}}},
+	}}
+	tablePks, err := d.collectLimitedTables(ctx, tables)
+	if err != nil {
+		return err
+	}
+	err = d.reportFilteredReferences(ctx, tablePks)
+	if err != nil {
+		return err
+	}
+	sortedTablePks := dfsSort(tablePks, tables)
+	err = d.checkPii(ctx, sortedTablePks)
+	if err != nil {
+		return err
+	}
+	slog.Info("Batch seed", "id", id, "output", output)
+	return d.exporter.ExportToPath(ctx, sortedTablePks, output)
+}
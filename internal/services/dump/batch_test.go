@@ -0,0 +1,148 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/repositories"
+	"github.com/t1m4/db_part_dump/internal/testutil"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildBatchOutput(t *testing.T) {
+	type TestData struct {
+		name     string
+		output   string
+		id       string
+		expected string
+	}
+	tests := []TestData{
+		{name: "test int id", output: "backups/user_{{id}}.sql", id: "42", expected: "backups/user_42.sql"},
+		{name: "test id with separator", output: "user_{{id}}.sql", id: "a/b", expected: "user_a_b.sql"},
+	}
+	for _, test := range tests {
+		actual := buildBatchOutput(test.output, test.id)
+		if actual != test.expected {
+			t.Errorf("%s: got %s, expected %s", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestBuildBatchOutputs(t *testing.T) {
+	type TestData struct {
+		name     string
+		ids      []string
+		expected []string
+		isErr    bool
+	}
+	tests := []TestData{
+		{name: "test unique outputs", ids: []string{"1", "2"}, expected: []string{"user_1.sql", "user_2.sql"}},
+		{name: "test same output after replace", ids: []string{"a/b", "a_b"}, isErr: true},
+		{name: "test same id", ids: []string{"1", "1"}, isErr: true},
+	}
+	for _, test := range tests {
+		actual, err := buildBatchOutputs("user_{{id}}.sql", test.ids)
+		if (err != nil) != test.isErr {
+			t.Errorf("%s: wrong err %v", test.name, err)
+		}
+		if diff := cmp.Diff(test.expected, actual); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", test.name, diff)
+		}
+	}
+}
+
+// Get first column values of COPY rows of table in dump file
+func readCopyIds(t *testing.T, path string, tableName string) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	ids := make([]string, 0)
+	isTableRows := false
+	for _, line := range strings.Split(string(content), "\n") {
+		switch {
+		case strings.HasPrefix(line, fmt.Sprintf("COPY %s ", tableName)):
+			isTableRows = true
+		case line == "\\.":
+			isTableRows = false
+		case isTableRows:
+			ids = append(ids, strings.Split(line, "\t")[0])
+		}
+	}
+	return ids
+}
+
+func TestStartBatchDump(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+	dir := t.TempDir()
+	c := &config.Config{
+		Settings: config.Settings{
+			SchemaName: "alpha",
+			Direction:  constants.OUTGOING,
+			Batch: config.Batch{
+				Table:       "orders",
+				Ids:         []string{"1", "3", "5"},
+				Output:      filepath.Join(dir, "order_{{id}}.sql"),
+				Concurrency: 2,
+			},
+		},
+	}
+
+	err := New(c, repos).StartDump(ctx)
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	expectedUserIds := map[string][]string{"1": {"1"}, "3": {"2"}, "5": {"4"}}
+	for _, id := range c.Settings.Batch.Ids {
+		output := buildBatchOutput(c.Settings.Batch.Output, id)
+		if diff := cmp.Diff([]string{id}, readCopyIds(t, output, "alpha.orders")); diff != "" {
+			t.Errorf("seed %s orders mismatch (-want +got):\n%s", id, diff)
+		}
+		if diff := cmp.Diff(expectedUserIds[id], readCopyIds(t, output, "alpha.users")); diff != "" {
+			t.Errorf("seed %s users mismatch (-want +got):\n%s", id, diff)
+		}
+	}
+}
+
+func TestStartBatchDumpWithFailedSeeds(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+	dir := t.TempDir()
+	c := &config.Config{
+		Settings: config.Settings{
+			SchemaName: "alpha",
+			Direction:  constants.OUTGOING,
+			Batch: config.Batch{
+				Table:  "orders",
+				Column: "missing_column",
+				Ids:    []string{"1", "3"},
+				Output: filepath.Join(dir, "order_{{id}}.sql"),
+			},
+		},
+	}
+
+	err := New(c, repos).StartDump(ctx)
+	if err == nil {
+		t.Fatalf("wrong err: %v, expected error", err)
+	}
+	// Failed seeds leave neither outputs nor temporary files
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	if len(entries) != 0 {
+		t.Errorf("wrong files count %d, expected 0", len(entries))
+	}
+}
@@ -0,0 +1,127 @@
+package dump
+
+import (
+	"context"
+	"log/slog"
+	"math"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/schemas"
+)
+
+// Count of binary search steps for fraction of seed rows
+const budgetSearchSteps = 10
+
+// Root table with count of rows which can be seeds
+type budgetRoot struct {
+	name      string
+	rowsCount int64
+}
+
+// Build starting tables with fraction of rows of every root table as random sample with seed.
+// Random order is the same on every step, so bigger fraction keeps seeds of smaller one.
+// Every root keeps at least one seed
+func buildBudgetTables(roots []budgetRoot, fraction float64, seed int64) []config.Table {
+	tables := make([]config.Table, 0, len(roots))
+	for _, root := range roots {
+		if root.rowsCount == 0 {
+			continue
+		}
+		count := max(int(math.Ceil(fraction*float64(root.rowsCount))), 1)
+		tables = append(tables, config.Table{
+			Name:   root.name,
+			Sample: config.Sample{Rows: count, Method: constants.RANDOM, Seed: seed},
+		})
+	}
+	return tables
+}
+
+// Collect tables with as many seed rows of root tables as fit in budget size.
+// Fraction of seed rows is found by binary search, every step collects tables completely
+func (d *DumpService) collectBudgetTables(ctx context.Context) (tablePksByTableT, []config.Table, error) {
+	targetSize, err := d.getBudgetSize(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	roots, err := d.getBudgetRoots(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var bestTablePks tablePksByTableT
+	var bestTables []config.Table
+	low, high := 0.0, 1.0
+	fraction := high
+	for step := 0; step <= budgetSearchSteps; step++ {
+		tables := buildBudgetTables(roots, fraction, d.c.Settings.Budget.Seed)
+		tablePks, err := d.collectLimitedTables(ctx, tables)
+		if err != nil {
+			return nil, nil, err
+		}
+		size, err := d.getDumpSize(ctx, tablePks)
+		if err != nil {
+			return nil, nil, err
+		}
+		slog.Info("Budget step", "fraction", fraction, "size", size, "target_size", targetSize)
+		if size <= targetSize {
+			bestTablePks, bestTables = tablePks, tables
+			if fraction == high {
+				break
+			}
+			low = fraction
+		} else {
+			high = fraction
+		}
+		fraction = (low + high) / 2
+	}
+	if bestTablePks == nil {
+		slog.Warn("Dump with one seed row per root table exceeds budget", "target_size", targetSize)
+		tables := buildBudgetTables(roots, 0, d.c.Settings.Budget.Seed)
+		tablePks, err := d.collectLimitedTables(ctx, tables)
+		if err != nil {
+			return nil, nil, err
+		}
+		return tablePks, tables, nil
+	}
+	return bestTablePks, bestTables, nil
+}
+
+// Get budget size in bytes from size or percent of schema size
+func (d *DumpService) getBudgetSize(ctx context.Context) (int64, error) {
+	budget := d.c.Settings.Budget
+	if budget.Size != "" {
+		return config.ParseSize(budget.Size)
+	}
+	schemaSize, err := d.repo.GetSchemaSize(ctx, d.c.Settings.SchemaName)
+	if err != nil {
+		return 0, err
+	}
+	return int64(float64(schemaSize) * budget.Percent / 100), nil
+}
+
+// Get rows count of root tables
+func (d *DumpService) getBudgetRoots(ctx context.Context) ([]budgetRoot, error) {
+	roots := make([]budgetRoot, 0, len(d.c.Settings.Budget.Tables))
+	for _, tableName := range d.c.Settings.Budget.Tables {
+		table := &schemas.Table{Name: tableName, IsFull: true}
+		rowsCount, err := d.repo.GetRowsCount(ctx, d.c.Settings.SchemaName, table)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, budgetRoot{name: tableName, rowsCount: rowsCount})
+	}
+	return roots, nil
+}
+
+// Get size of all dump rows in bytes
+func (d *DumpService) getDumpSize(ctx context.Context, tablePksByTable tablePksByTableT) (int64, error) {
+	var size int64
+	for _, table := range tablePksByTable {
+		tableSize, err := d.repo.GetRowsSize(ctx, d.c.Settings.SchemaName, table)
+		if err != nil {
+			return 0, err
+		}
+		size += tableSize
+	}
+	return size, nil
+}
@@ -0,0 +1,76 @@
+package dump
+
+import (
+	"context"
+	"testing"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/repositories"
+	"github.com/t1m4/db_part_dump/internal/testutil"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildBudgetTables(t *testing.T) {
+	type TestData struct {
+		name     string
+		fraction float64
+		expected []config.Table
+	}
+	roots := []budgetRoot{
+		{name: "users", rowsCount: 4},
+		{name: "coupons", rowsCount: 0},
+	}
+	tests := []TestData{
+		{
+			name:     "test half",
+			fraction: 0.5,
+			expected: []config.Table{{Name: "users", Sample: config.Sample{Rows: 2, Method: constants.RANDOM, Seed: 42}}},
+		},
+		{
+			name:     "test at least one seed",
+			fraction: 0,
+			expected: []config.Table{{Name: "users", Sample: config.Sample{Rows: 1, Method: constants.RANDOM, Seed: 42}}},
+		},
+	}
+	for _, test := range tests {
+		actual := buildBudgetTables(roots, test.fraction, 42)
+		if diff := cmp.Diff(test.expected, actual); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", test.name, diff)
+		}
+	}
+}
+
+func TestCollectBudgetTables(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+	c := &config.Config{
+		Settings: config.Settings{
+			SchemaName: "alpha",
+			Direction:  constants.OUTGOING,
+			Budget:     config.Budget{Tables: []string{"orders"}, Size: "1KB", Seed: 1},
+		},
+	}
+
+	dumpService := New(c, repos)
+	tablePks, tables, err := dumpService.collectBudgetTables(ctx)
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	if len(tables) != 1 || tables[0].Name != "orders" {
+		t.Errorf("wrong starting tables: %+v", tables)
+	}
+	size, err := dumpService.getDumpSize(ctx, tablePks)
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+	if size > 1024 {
+		t.Errorf("dump size %d exceeds budget %d", size, 1024)
+	}
+	if _, ok := tablePks["users"]; !ok {
+		t.Errorf("referenced users table is not in dump")
+	}
+}
@@ -0,0 +1,167 @@
+package dump
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/db"
+	"github.com/t1m4/db_part_dump/internal/repositories"
+	"github.com/t1m4/db_part_dump/internal/schemas"
+)
+
+// Days of date shift of rows reached from starting row. Offset is never 0
+func buildDateShift(dateShift config.DateShift, tableName string, seedId string) int {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%d:%s:%s", dateShift.Seed, tableName, seedId)))
+	number := binary.BigEndian.Uint64(hash[:8])
+	days := int(number%uint64(dateShift.MaxDays)) + 1
+	if number>>63 == 1 {
+		return -days
+	}
+	return days
+}
+
+// Starting row which dumped row is reached from
+type rowOrigin struct {
+	table string
+	id    string
+}
+
+// Compare by table and id. Integer ids are compared as numbers, so 2 is before 10
+func (o rowOrigin) less(other rowOrigin) bool {
+	if o.table != other.table {
+		return o.table < other.table
+	}
+	number, err := strconv.ParseInt(o.id, 10, 64)
+	otherNumber, otherErr := strconv.ParseInt(other.id, 10, 64)
+	if err == nil && otherErr == nil {
+		return number < otherNumber
+	}
+	return o.id < other.id
+}
+
+// Origins of dumped rows recorded during traversal, so date shifts are assigned in one pass.
+// Row reached from several starting rows gets origin which reaches it first,
+// ties within one traversal step are broken by smaller starting row
+type rowOrigins struct {
+	byColumn map[string]map[string]map[string]rowOrigin // Origins of filter ids by table and column
+	byPk     map[string]map[string]rowOrigin            // Origins of rows by table and pk value of exported row
+}
+
+func newRowOrigins() *rowOrigins {
+	return &rowOrigins{
+		byColumn: make(map[string]map[string]map[string]rowOrigin),
+		byPk:     make(map[string]map[string]rowOrigin),
+	}
+}
+
+// Set origin of filter id if it has no origin yet
+func (o *rowOrigins) set(tableName string, columnName string, id string, origin rowOrigin) {
+	if _, ok := o.byColumn[tableName]; !ok {
+		o.byColumn[tableName] = make(map[string]map[string]rowOrigin)
+	}
+	if _, ok := o.byColumn[tableName][columnName]; !ok {
+		o.byColumn[tableName][columnName] = make(map[string]rowOrigin)
+	}
+	if _, ok := o.byColumn[tableName][columnName][id]; !ok {
+		o.byColumn[tableName][columnName][id] = origin
+	}
+}
+
+// Starting rows are own origins
+func (o *rowOrigins) addStarting(tableName string, pkColumnName string, pkIds schemas.Pks) {
+	for id := range pkIds {
+		o.set(tableName, pkColumnName, id, rowOrigin{table: tableName, id: id})
+	}
+}
+
+// Get columns which are selected with fk columns to find origins of table rows:
+// pk, filter columns and other columns with known origins in name order
+func (o *rowOrigins) columnNames(table config.Table, pkColumnName string) []string {
+	otherColumnNames := make([]string, 0, len(o.byColumn[table.Name]))
+	for columnName := range o.byColumn[table.Name] {
+		otherColumnNames = append(otherColumnNames, columnName)
+	}
+	sort.Strings(otherColumnNames)
+	columnNames := []string{pkColumnName}
+	for _, filter := range table.Filters {
+		columnNames = append(columnNames, filter.Name)
+	}
+	return append(columnNames, otherColumnNames...)
+}
+
+// Find origins of selected table rows. Columns are checked in order of columnNames, so pk goes first
+// and starting rows keep own origins. Row which is already attributed keeps its origin
+func (o *rowOrigins) addRows(table config.Table, pkColumnName string, rows []map[string]any) []*rowOrigin {
+	columnNames := o.columnNames(table, pkColumnName)
+	if _, ok := o.byPk[table.Name]; !ok {
+		o.byPk[table.Name] = make(map[string]rowOrigin)
+	}
+	result := make([]*rowOrigin, len(rows))
+	for i, row := range rows {
+		key := repositories.AnyToPsqlString(row[pkColumnName])
+		if origin, ok := o.byPk[table.Name][key]; ok {
+			result[i] = &origin
+			continue
+		}
+		for _, columnName := range columnNames {
+			if row[columnName] == nil {
+				continue
+			}
+			if origin, ok := o.byColumn[table.Name][columnName][AnyToString(row[columnName])]; ok {
+				o.byPk[table.Name][key] = origin
+				result[i] = &origin
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Set origins of fk ids referenced by table rows
+func (o *rowOrigins) addFkIds(fk db.Fk, rows []map[string]any, origins []*rowOrigin) {
+	candidates := make(map[string]rowOrigin)
+	for i, row := range rows {
+		if origins[i] == nil || row[fk.ColumnName] == nil {
+			continue
+		}
+		id := AnyToString(row[fk.ColumnName])
+		if current, ok := candidates[id]; !ok || origins[i].less(current) {
+			candidates[id] = *origins[i]
+		}
+	}
+	for id, origin := range candidates {
+		o.set(fk.ForeignTableName, fk.ForeignColumnName, id, origin)
+	}
+}
+
+// Set date shifts of dumped rows by their origins,
+// so dates of all rows of one root entity are shifted together
+func (d *DumpService) addDateShifts(ctx context.Context, tablePks tablePksByTableT, origins *rowOrigins) error {
+	daysByOrigin := make(map[rowOrigin]int)
+	for tableName, table := range tablePks {
+		rowsOrigins := origins.byPk[tableName]
+		if table.IsFull || len(rowsOrigins) == 0 {
+			continue
+		}
+		pkColumnName, err := d.getPkColumnName(ctx, tableName)
+		if err != nil {
+			return err
+		}
+		table.ShiftColumn = pkColumnName
+		table.DateShifts = make(schemas.DateShifts, len(rowsOrigins))
+		for key, origin := range rowsOrigins {
+			days, ok := daysByOrigin[origin]
+			if !ok {
+				days = buildDateShift(d.c.Settings.DateShift, origin.table, origin.id)
+				daysByOrigin[origin] = days
+			}
+			table.DateShifts[key] = days
+		}
+	}
+	return nil
+}
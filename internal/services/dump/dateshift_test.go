@@ -0,0 +1,109 @@
+package dump
+
+import (
+	"context"
+	"testing"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+	"github.com/t1m4/db_part_dump/internal/repositories"
+	"github.com/t1m4/db_part_dump/internal/schemas"
+	"github.com/t1m4/db_part_dump/internal/testutil"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildDateShift(t *testing.T) {
+	dateShift := config.DateShift{MaxDays: 30, Seed: 42}
+	for _, seedId := range []string{"1", "2", "'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11'"} {
+		days := buildDateShift(dateShift, "users", seedId)
+		if days == 0 || days < -30 || days > 30 {
+			t.Errorf("seed %s: days %d out of range", seedId, days)
+		}
+		if other := buildDateShift(dateShift, "users", seedId); other != days {
+			t.Errorf("seed %s: days are not deterministic %d, %d", seedId, days, other)
+		}
+	}
+}
+
+func TestRowOriginLess(t *testing.T) {
+	type TestData struct {
+		name     string
+		origin   rowOrigin
+		other    rowOrigin
+		expected bool
+	}
+	tests := []TestData{
+		{name: "test int ids", origin: rowOrigin{"users", "2"}, other: rowOrigin{"users", "10"}, expected: true},
+		{name: "test text ids", origin: rowOrigin{"users", "'b'"}, other: rowOrigin{"users", "'a'"}, expected: false},
+		{name: "test tables", origin: rowOrigin{"orders", "10"}, other: rowOrigin{"users", "1"}, expected: true},
+	}
+	for _, test := range tests {
+		if actual := test.origin.less(test.other); actual != test.expected {
+			t.Errorf("%s: got %v, expected %v", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestRowOrigins(t *testing.T) {
+	origins := newRowOrigins()
+	origins.addStarting("users", "id", schemas.Pks{"2": true, "10": true})
+	users := config.Table{Name: "users", Filters: []config.Filter{{Name: "id", Values: []string{"2", "10"}}}}
+	userRows := []map[string]any{{"id": int64(10), "country_id": int64(1)}, {"id": int64(2), "country_id": int64(1)}}
+	userOrigins := origins.addRows(users, "id", userRows)
+	// Country is shared by both users, smaller user wins
+	fk := db.Fk{ColumnName: "country_id", ForeignTableName: "countries", ForeignColumnName: "id"}
+	origins.addFkIds(fk, userRows, userOrigins)
+	countries := config.Table{Name: "countries", Filters: []config.Filter{{Name: "id", Values: []string{"1"}}}}
+	origins.addRows(countries, "id", []map[string]any{{"id": int64(1)}})
+
+	expected := map[string]map[string]rowOrigin{
+		"users":     {"2": {"users", "2"}, "10": {"users", "10"}},
+		"countries": {"1": {"users", "2"}},
+	}
+	if diff := cmp.Diff(expected, origins.byPk, cmp.AllowUnexported(rowOrigin{})); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRowOriginsColumnNames(t *testing.T) {
+	origins := newRowOrigins()
+	origins.set("orders", "user_id", "1", rowOrigin{table: "users", id: "1"})
+	origins.set("orders", "coupon_id", "2", rowOrigin{table: "coupons", id: "2"})
+	orders := config.Table{Name: "orders", Filters: []config.Filter{{Name: "user_id", Values: []string{"1"}}}}
+	expected := []string{"id", "user_id", "coupon_id", "user_id"}
+	if diff := cmp.Diff(expected, origins.columnNames(orders, "id")); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCollectTablesWithDateShift(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+	tables := []config.Table{{Name: "users", Filters: []config.Filter{{Name: "id", Value: "1, 2"}}}}
+	c := &config.Config{
+		Settings: config.Settings{
+			SchemaName: "alpha",
+			Direction:  constants.OUTGOING,
+			Tables:     tables,
+			Rules:      []config.Rule{{Table: "users", ForeignTable: "orders", Direction: constants.INCOMING}},
+			DateShift:  config.DateShift{MaxDays: 100, Seed: 1},
+		},
+	}
+	tablePks, err := New(c, repos).collectTables(ctx, tables)
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	first := buildDateShift(c.Settings.DateShift, "users", "1")
+	second := buildDateShift(c.Settings.DateShift, "users", "2")
+	expected := schemas.DateShifts{"1": first, "2": first, "3": second}
+	if diff := cmp.Diff(expected, tablePks["orders"].DateShifts); diff != "" {
+		t.Errorf("orders date shifts mismatch (-want +got):\n%s", diff)
+	}
+	if tablePks["orders"].ShiftColumn != "id" {
+		t.Errorf("wrong shift column %s", tablePks["orders"].ShiftColumn)
+	}
+}
@@ -0,0 +1,64 @@
+package dump
+
+import (
+	"log/slog"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+	"github.com/t1m4/db_part_dump/internal/schemas"
+)
+
+// Table in traversal queue with count of fks from starting table
+type queueTable struct {
+	config.Table
+	depth    int
+	maxDepth int
+	// Only outgoing fks are followed, e.g. from full tables and rows referenced by them
+	isOutgoingOnly bool
+}
+
+func (q queueTable) isDepthExceeded() bool {
+	return q.maxDepth != 0 && q.depth >= q.maxDepth
+}
+
+func (q queueTable) next(table config.Table) queueTable {
+	return queueTable{Table: table, depth: q.depth + 1, maxDepth: q.maxDepth, isOutgoingOnly: q.isOutgoingOnly}
+}
+
+// Keep only outgoing fks which are required for referential integrity after max depth.
+// With nullify depth overflow nullable outgoing fks are set to NULL instead of following
+func (d *DumpService) filterDepthFks(table *schemas.Table, fks []db.Fk) []db.Fk {
+	result := make([]db.Fk, 0, len(fks))
+	for _, fk := range fks {
+		if fk.Direction != constants.OUTGOING {
+			continue
+		}
+		if d.c.Settings.DepthOverflow == constants.NULLIFY && fk.IsNullable {
+			if _, ok := table.NullFks[fk.ColumnName]; ok {
+				continue
+			}
+			slog.Warn(
+				"Max depth reached, fk is set to NULL",
+				"table", table.Name,
+				"column", fk.ColumnName,
+				"foreign_table", fk.ForeignTableName,
+			)
+			addNullFk(table, fk)
+			continue
+		}
+		result = append(result, fk)
+	}
+	return result
+}
+
+// Keep only outgoing fks
+func filterOutgoingFks(fks []db.Fk) []db.Fk {
+	result := make([]db.Fk, 0, len(fks))
+	for _, fk := range fks {
+		if fk.Direction == constants.OUTGOING {
+			result = append(result, fk)
+		}
+	}
+	return result
+}
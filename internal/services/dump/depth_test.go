@@ -0,0 +1,69 @@
+package dump
+
+import (
+	"testing"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+	"github.com/t1m4/db_part_dump/internal/schemas"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFilterDepthFks(t *testing.T) {
+	type TestData struct {
+		name            string
+		depthOverflow   string
+		expectedFks     []db.Fk
+		expectedNullFks map[string]schemas.NullFk
+	}
+	usersFk := db.Fk{ColumnName: "user_id", ForeignTableName: "users", ForeignColumnName: "id", Direction: constants.OUTGOING}
+	ordersFk := db.Fk{
+		ColumnName:        "order_id",
+		ForeignTableName:  "orders",
+		ForeignColumnName: "id",
+		Direction:         constants.OUTGOING,
+		IsNullable:        true,
+	}
+	childFk := db.Fk{ColumnName: "id", ForeignTableName: "payments", ForeignColumnName: "method_id", Direction: constants.INCOMING}
+	tests := []TestData{
+		{
+			name:          "test follow",
+			depthOverflow: constants.FOLLOW,
+			expectedFks:   []db.Fk{usersFk, ordersFk},
+		},
+		{
+			name:            "test nullify",
+			depthOverflow:   constants.NULLIFY,
+			expectedFks:     []db.Fk{usersFk},
+			expectedNullFks: map[string]schemas.NullFk{"order_id": {ForeignTableName: "orders", ForeignColumnName: "id"}},
+		},
+	}
+	for _, test := range tests {
+		d := &DumpService{c: &config.Config{Settings: config.Settings{DepthOverflow: test.depthOverflow}}}
+		table := &schemas.Table{Name: "user_payment_methods"}
+		actual := d.filterDepthFks(table, []db.Fk{usersFk, ordersFk, childFk})
+		if diff := cmp.Diff(test.expectedFks, actual); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", test.name, diff)
+		}
+		if diff := cmp.Diff(test.expectedNullFks, table.NullFks); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", test.name, diff)
+		}
+	}
+}
+
+func TestQueueTableDepth(t *testing.T) {
+	starting := queueTable{Table: config.Table{Name: "users"}, maxDepth: 1}
+	if starting.isDepthExceeded() {
+		t.Errorf("starting table depth is exceeded")
+	}
+	next := starting.next(config.Table{Name: "orders"})
+	if !next.isDepthExceeded() {
+		t.Errorf("next table depth is not exceeded")
+	}
+	unlimited := queueTable{Table: config.Table{Name: "users"}, depth: 100}
+	if unlimited.isDepthExceeded() {
+		t.Errorf("unlimited table depth is exceeded")
+	}
+}
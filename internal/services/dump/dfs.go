@@ -1,6 +1,8 @@
 package dump
 
 import (
+	"sort"
+
 	"github.com/t1m4/db_part_dump/config"
 	"github.com/t1m4/db_part_dump/internal/schemas"
 )
@@ -22,12 +24,33 @@ func dfsSort(tablePksByTable tablePksByTableT, configTables []config.Table) []*s
 	resultTablePks := make([]*schemas.Table, 0)
 	visited := make(map[string]bool, len(tablePksByTable))
 
-	// Create queue this starting elements
+	// Create queue this starting elements. Full tables go first
 	startingTables := make([]*schemas.Table, 0)
 	configTablesSet := make(map[string]bool, len(configTables))
+	fullTableNames := make([]string, 0)
+	for tableName, table := range tablePksByTable {
+		if table.IsFull {
+			fullTableNames = append(fullTableNames, tableName)
+		}
+	}
+	sort.Strings(fullTableNames)
+	for _, tableName := range fullTableNames {
+		startingTables = append(startingTables, tablePksByTable[tableName])
+		configTablesSet[tableName] = true
+	}
 	for _, table := range configTables {
-		startingTables = append(startingTables, tablePksByTable[table.Name])
-		configTablesSet[table.Name] = true
+		tableName := table.Name
+		if table.BaseTable != "" {
+			tableName = table.BaseTable
+		}
+		if _, ok := configTablesSet[tableName]; ok {
+			continue
+		}
+		if _, ok := tablePksByTable[tableName]; !ok {
+			continue
+		}
+		startingTables = append(startingTables, tablePksByTable[tableName])
+		configTablesSet[tableName] = true
 	}
 	for tableName, table := range tablePksByTable {
 		if _, ok := configTablesSet[tableName]; ok {
@@ -24,6 +24,13 @@ func TestDfsSort(t *testing.T) {
 			userTable.Name: userTable, ordersTable.Name: ordersTable,
 		},
 	}
+	couponsTable := &schemas.Table{Name: "coupons", IsFull: true}
+	orderCouponsTable := &schemas.Table{
+		Name: "order_coupons",
+		Fks: map[string]*schemas.Table{
+			ordersTable.Name: ordersTable, couponsTable.Name: couponsTable,
+		},
+	}
 	tests := []TestData{
 		{
 			name: "test dfs",
@@ -35,6 +42,17 @@ func TestDfsSort(t *testing.T) {
 			configTables: []config.Table{{Name: userPaymentMethodsTable.Name}},
 			expected:     []*schemas.Table{userTable, ordersTable, userPaymentMethodsTable},
 		},
+		{
+			name: "test full tables first",
+			tablePksByTable: tablePksByTableT{
+				userTable.Name:         userTable,
+				ordersTable.Name:       ordersTable,
+				orderCouponsTable.Name: orderCouponsTable,
+				couponsTable.Name:      couponsTable,
+			},
+			configTables: []config.Table{{Name: orderCouponsTable.Name}},
+			expected:     []*schemas.Table{couponsTable, userTable, ordersTable, orderCouponsTable},
+		},
 	}
 	for _, test := range tests {
 		actual := dfsSort(test.tablePksByTable, test.configTables)
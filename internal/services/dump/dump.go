@@ -2,8 +2,12 @@ package dump
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"sync"
 
 	"github.com/t1m4/db_part_dump/config"
 	"github.com/t1m4/db_part_dump/internal/constants"
@@ -11,28 +15,71 @@ import (
 	"github.com/t1m4/db_part_dump/internal/repositories"
 	"github.com/t1m4/db_part_dump/internal/schemas"
 	"github.com/t1m4/db_part_dump/internal/services/exporter"
+	"github.com/t1m4/db_part_dump/internal/services/relations"
 )
 
 type fksByTableT map[string][]db.Fk
 type tablePksByTableT map[string]*schemas.Table
 
 type DumpService struct {
-	c        *config.Config
-	repo     repositories.RepositoriesI
-	exporter exporter.Exporter
+	c             *config.Config
+	repo          repositories.RepositoriesI
+	exporter      exporter.Exporter
+	relations     []config.Relation
+	rulesByTable  rulesByTableT
+	tablePatterns *tablePatterns
+	initOnce      sync.Once
+	initErr       error
+	// Catalog caches shared by all collections of service
+	cacheMu       sync.Mutex
+	pkColumnNames map[string]string
+	fksByTable    fksByTableT
 }
 
 func New(c *config.Config, repo *repositories.Repositories) *DumpService {
-	return &DumpService{c: c, repo: repo, exporter: exporter.New(c, repo)}
+	repo.SetDefaultFilters(newDefaultFilters(c.Settings.TableSettings))
+	return &DumpService{
+		c:             c,
+		repo:          repo,
+		exporter:      exporter.New(c, repo),
+		rulesByTable:  newRulesByTable(c.Settings.Rules),
+		pkColumnNames: make(map[string]string),
+		fksByTable:    make(fksByTableT),
+	}
 }
 
 // Starting point of service
 func (d *DumpService) StartDump(ctx context.Context) error {
-	tablePks, err := d.collectTableFkIds(ctx)
+	if d.c.Settings.Batch.IsEnabled() {
+		return d.startBatchDump(ctx)
+	}
+	var tablePks tablePksByTableT
+	var err error
+	tables := d.c.Settings.Tables
+	if d.c.Settings.TenantColumn != "" {
+		tenantTables, err := d.getTenantTables(ctx)
+		if err != nil {
+			return err
+		}
+		tables = append(slices.Clone(tables), tenantTables...)
+	}
+	if d.c.Settings.Budget.IsEnabled() {
+		tablePks, tables, err = d.collectBudgetTables(ctx)
+	} else {
+		tablePks, err = d.collectLimitedTables(ctx, tables)
+	}
+	if err != nil {
+		return err
+	}
+	err = d.reportFilteredReferences(ctx, tablePks)
+	if err != nil {
+		return err
+	}
+	sortedTablePks := dfsSort(tablePks, tables)
+	err = d.checkPii(ctx, sortedTablePks)
 	if err != nil {
 		return err
 	}
-	sortedTablePks := dfsSort(tablePks, d.c.Settings.Tables)
 	err = d.exporter.ExportToFile(ctx, sortedTablePks)
 	if err != nil {
 		return err
@@ -41,122 +88,330 @@ func (d *DumpService) StartDump(ctx context.Context) error {
 
 }
 
+// Collect table pks using starting tables and apply table row caps
+func (d *DumpService) collectLimitedTables(ctx context.Context, tables []config.Table) (tablePksByTableT, error) {
+	tablePks, err := d.collectTables(ctx, tables)
+	if err != nil {
+		return nil, err
+	}
+	err = d.limitTables(ctx, tablePks)
+	if err != nil {
+		return nil, err
+	}
+	return tablePks, nil
+}
+
 // Collect all table pks using config tables
 func (d *DumpService) collectTableFkIds(ctx context.Context) (tablePksByTableT, error) {
-	fksByTable := make(fksByTableT)
-	var err error
+	return d.collectTables(ctx, d.c.Settings.Tables)
+}
+
+// Collect all table pks using starting tables
+func (d *DumpService) collectTables(ctx context.Context, tables []config.Table) (tablePksByTableT, error) {
 	var newTables []config.Table
-	tablePksByTable := make(tablePksByTableT, len(d.c.Settings.Tables))
-	includeIncomingTables := make(map[string]bool, len(d.c.Settings.IncludeIncomingTables))
-	for _, tableName := range d.c.Settings.IncludeIncomingTables {
-		includeIncomingTables[tableName] = true
+	tablePksByTable := make(tablePksByTableT, len(tables))
+	err := d.init(ctx)
+	if err != nil {
+		return nil, err
 	}
-	tablesQueue, err := d.initTables(ctx, tablePksByTable)
+	fullTables, err := d.initFullTables(ctx, tablePksByTable)
 	if err != nil {
 		return nil, err
 	}
+	var origins *rowOrigins
+	if d.c.Settings.DateShift.IsEnabled() {
+		origins = newRowOrigins()
+	}
+	startingTables, err := d.initTables(ctx, tablePksByTable, tables, origins)
+	if err != nil {
+		return nil, err
+	}
+	tablesQueue := make([]queueTable, 0, len(startingTables)+len(fullTables))
+	for _, table := range startingTables {
+		maxDepth := d.c.Settings.MaxDepth
+		if table.MaxDepth != 0 {
+			maxDepth = table.MaxDepth
+		}
+		tablesQueue = append(tablesQueue, queueTable{Table: table, maxDepth: maxDepth, isOutgoingOnly: table.OutgoingOnly})
+	}
+	for _, table := range fullTables {
+		tablesQueue = append(tablesQueue, queueTable{Table: table, isOutgoingOnly: true})
+	}
 
 	i := 0
 	for len(tablesQueue) != 0 {
 		slog.Debug("")
-		table := tablesQueue[0]
+		queueItem := tablesQueue[0]
+		table := queueItem.Table
 		tablesQueue = tablesQueue[1:]
 		slog.Debug(fmt.Sprintf("\nStarted %s", table.Name))
-		fks, err := d.getFks(ctx, fksByTable, table.Name, includeIncomingTables[table.Name])
+		fks, err := d.getFks(ctx, table.Name, d.tablePatterns.isIncludeIncoming(table.Name))
 		if err != nil {
 			return nil, err
 		}
-		if len(fks) == 0 {
+		if queueItem.isOutgoingOnly {
+			fks = filterOutgoingFks(fks)
+		}
+		if queueItem.isDepthExceeded() {
+			fks = d.filterDepthFks(tablePksByTable[table.Name], fks)
+		}
+		// Rows without fks are still selected to record their origins
+		if len(fks) == 0 && origins == nil {
 			continue
 		}
-		newTables, err = d.getFksIds(ctx, tablePksByTable, fks, table)
+		newTables, err = d.getFksIds(ctx, tablePksByTable, fks, table, origins)
 		if err != nil {
 			return nil, err
 		}
-		tablesQueue = append(tablesQueue, newTables...)
+		for _, newTable := range newTables {
+			tablesQueue = append(tablesQueue, queueItem.next(newTable))
+		}
 		i++
 	}
 	// d.debugTables(tablePksByTable)
+	if origins != nil {
+		err = d.addDateShifts(ctx, tablePksByTable, origins)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return tablePksByTable, nil
 }
 
-// Init config table pk ids
-func (d *DumpService) initTables(ctx context.Context, tablePksByTable tablePksByTableT) ([]config.Table, error) {
-	tablesQueue := make([]config.Table, len(d.c.Settings.Tables))
-	copy(tablesQueue, d.c.Settings.Tables)
-	for _, table := range tablesQueue {
+// Init table patterns, mask checks, relations and rule checks once for all collections
+func (d *DumpService) init(ctx context.Context) error {
+	d.initOnce.Do(func() {
+		d.tablePatterns, d.initErr = newTablePatterns(d.c.Settings)
+		if d.initErr != nil {
+			return
+		}
+		d.initErr = d.checkMasks(ctx)
+		if d.initErr != nil {
+			return
+		}
+		d.relations, d.initErr = d.loadRelations(ctx)
+		if d.initErr != nil {
+			return
+		}
+		d.exporter.SetRelations(d.relations)
+		d.initErr = d.checkRules(ctx)
+	})
+	return d.initErr
+}
+
+// Init starting table pk ids.
+// Starting view is resolved to rows of its base table by key column.
+// Starting rows are recorded as own origins when origins are collected
+func (d *DumpService) initTables(
+	ctx context.Context,
+	tablePksByTable tablePksByTableT,
+	tables []config.Table,
+	origins *rowOrigins,
+) ([]config.Table, error) {
+	tablesQueue := make([]config.Table, 0, len(tables))
+	for _, table := range tables {
+		tableName := table.Name
+		if table.BaseTable != "" {
+			tableName = table.BaseTable
+		}
+		// Full tables are already dumped completely
+		if tablePks, ok := tablePksByTable[tableName]; ok && tablePks.IsFull {
+			continue
+		}
 		// Get pk column name
-		pkColumnName, err := d.repo.GetPKColumnName(ctx, d.c.Settings.SchemaName, table.Name)
+		pkColumnName, err := d.getPkColumnName(ctx, tableName)
 		if err != nil {
 			return nil, err
 		}
+		keyColumnName := pkColumnName
+		if table.KeyColumn != "" {
+			keyColumnName = table.KeyColumn
+		}
 		// Get table ids using select pk ids
-		pkIdRows, err := d.repo.GetPkIdRows(ctx, d.c.Settings.SchemaName, table, pkColumnName)
+		var pkIdRows []map[string]any
+		if table.Query != "" {
+			pkIdRows, err = d.repo.GetQueryPkIdRows(ctx, table.Query, keyColumnName)
+		} else if table.Sample.Rows > 0 {
+			pkIdRows, err = d.getSamplePkIdRows(ctx, table, keyColumnName)
+		} else {
+			pkIdRows, err = d.repo.GetPkIdRows(ctx, d.c.Settings.SchemaName, table, keyColumnName)
+		}
 		if err != nil {
 			return nil, err
 		}
 
 		slog.Debug("DATA", "pkIds", pkIdRows)
-		currentPkIds := d.createIdsSet(pkIdRows, pkColumnName)
-		tablePksByTable[table.Name] = &schemas.Table{
-			Name:    table.Name,
-			Filters: map[string]schemas.Pks{pkColumnName: currentPkIds},
-			Fks:     make(map[string]*schemas.Table, 0),
+		currentPkIds := d.createIdsSet(pkIdRows, keyColumnName)
+		if tablePks, ok := tablePksByTable[tableName]; ok {
+			// Table can be starting several times, e.g. configured table and tenant table
+			addPks(tablePks, pkColumnName, currentPkIds)
+		} else {
+			tablePksByTable[tableName] = &schemas.Table{
+				Name:    tableName,
+				Filters: map[string]schemas.Pks{pkColumnName: currentPkIds},
+				Fks:     make(map[string]*schemas.Table, 0),
+			}
+		}
+		slog.Debug("PkIds", tableName, currentPkIds)
+		if origins != nil {
+			origins.addStarting(tableName, pkColumnName, currentPkIds)
 		}
-		slog.Debug("PkIds", table.Name, currentPkIds)
+		if table.Query != "" || table.Sample.Rows > 0 || table.BaseTable != "" {
+			if len(currentPkIds) == 0 {
+				continue
+			}
+			// Traversal continues from queried, sampled or view rows only
+			table = config.Table{
+				Name:         tableName,
+				Filters:      []config.Filter{{Name: pkColumnName, Values: repositories.PksToValues(currentPkIds)}},
+				MaxDepth:     table.MaxDepth,
+				OutgoingOnly: table.OutgoingOnly,
+			}
+		}
+		tablesQueue = append(tablesQueue, table)
 	}
 	return tablesQueue, nil
 }
 
-// Get table fks by tableName
+// Get table fks by tableName filtered by direction rules and excluded tables
 func (d *DumpService) getFks(
 	ctx context.Context,
-	fksByTable fksByTableT,
 	tableName string,
 	isIncludeIncoming bool,
 ) ([]db.Fk, error) {
 	var err error
-	fks, ok := fksByTable[tableName]
+	if d.tablePatterns.isStop(tableName) {
+		return []db.Fk{}, nil
+	}
+	d.cacheMu.Lock()
+	fks, ok := d.fksByTable[tableName]
+	d.cacheMu.Unlock()
 	if !ok {
-		fks, err = d.repo.GetFKs(ctx, d.c.Settings.Direction, d.c.Settings.SchemaName, tableName, isIncludeIncoming)
+		rules := d.rulesByTable[tableName]
+		defaultDirection := d.c.Settings.Direction
+		if defaultDirection == constants.INCOMING || isIncludeIncoming {
+			defaultDirection = constants.BOTH
+		}
+		isFollowOneToOne := d.c.Settings.FollowOneToOne
+		isQueryIncoming := defaultDirection != constants.OUTGOING || hasIncomingRule(rules) || isFollowOneToOne
+		fks, err = d.repo.GetFKs(ctx, d.c.Settings.Direction, d.c.Settings.SchemaName, tableName, isQueryIncoming)
 		if err != nil {
 			return nil, err
 		}
-		fksByTable[tableName] = fks
+		relationFks := relations.Fks(d.relations, d.c.Settings.SchemaName, tableName, isQueryIncoming)
+		fks = filterFks(mergeFks(fks, relationFks), rules, defaultDirection, isFollowOneToOne)
+		fks = d.tablePatterns.filterExcludedFks(fks)
+		d.cacheMu.Lock()
+		d.fksByTable[tableName] = fks
+		d.cacheMu.Unlock()
 		slog.Debug("DATA", "fks", fks)
 	}
 	return fks, nil
 }
 
+// Get virtual relations from config and inferred from column naming if enabled
+func (d *DumpService) loadRelations(ctx context.Context) ([]config.Relation, error) {
+	result := make([]config.Relation, len(d.c.Settings.Relations))
+	copy(result, d.c.Settings.Relations)
+	if !d.c.Settings.InferRelations {
+		return result, nil
+	}
+	columns, err := d.repo.GetColumns(ctx, d.c.Settings.SchemaName)
+	if err != nil {
+		return nil, err
+	}
+	for _, relation := range relations.Infer(columns) {
+		slog.Info(
+			"Inferred relation",
+			"table", relation.Table,
+			"column", relation.Column,
+			"foreign_table", relation.ForeignTable,
+			"foreign_column", relation.ForeignColumn,
+		)
+		result = append(result, relation)
+	}
+	return result, nil
+}
+
 // Collect fks ids and new tables by fks.
 // If table already visited and there is not new pks then do not add to queue again
-// Create fks relationships for dfs sorting
+// Create fks relationships for dfs sorting.
+// When origins are collected, origins of table rows are passed to fk ids in the same pass
 func (d *DumpService) getFksIds(
 	ctx context.Context,
 	tablePksByTable tablePksByTableT,
 	fks []db.Fk,
 	table config.Table,
+	origins *rowOrigins,
 ) ([]config.Table, error) {
-	fkIdRows, err := d.repo.GetFkIdRows(ctx, d.c.Settings.SchemaName, table, fks)
+	var pkColumnName string
+	var originColumnNames []string
+	if origins != nil {
+		var err error
+		pkColumnName, err = d.getPkColumnName(ctx, table.Name)
+		if err != nil {
+			return nil, err
+		}
+		originColumnNames = origins.columnNames(table, pkColumnName)
+	}
+	fkIdRows, err := d.repo.GetFkIdRows(ctx, d.c.Settings.SchemaName, table, fks, originColumnNames...)
 	if err != nil {
 		return nil, err
 	}
+	var rowsOrigins []*rowOrigin
+	if origins != nil {
+		rowsOrigins = origins.addRows(table, pkColumnName, fkIdRows)
+	}
 	resultTables := make([]config.Table, 0)
 	for _, fk := range fks {
+		if isNullifyFk(d.rulesByTable[table.Name], fk, d.c.Settings.NullifyNullableFks) {
+			addNullFk(tablePksByTable[table.Name], fk)
+			continue
+		}
 		currentFkIds := d.createIdsSet(fkIdRows, fk.ColumnName)
 		if len(currentFkIds) == 0 {
 			continue
 		}
+		if foreignTable, ok := tablePksByTable[fk.ForeignTableName]; ok && foreignTable.IsFull {
+			if fk.Direction == constants.OUTGOING {
+				tablePksByTable[table.Name].Fks[fk.ForeignTableName] = foreignTable
+			}
+			continue
+		}
+		if d.tablePatterns.isExcluded(fk.ForeignTableName) {
+			err = handleExcludedReference(tablePksByTable[table.Name], fk, d.c.Settings.ExcludedReference)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if origins != nil {
+			origins.addFkIds(fk, fkIdRows, rowsOrigins)
+		}
+		foreignColumnName := fk.ForeignColumnName
+		if rule, ok := findRule(d.rulesByTable[table.Name], fk); ok && isRuleRowsFiltered(rule, fk) {
+			// Rows referencing filtered-out rows stay in dump with fk set to NULL, nullability is checked by checkRules
+			if fk.Direction == constants.OUTGOING {
+				addNullFk(tablePksByTable[table.Name], fk)
+			}
+			foreignColumnName, currentFkIds, err = d.getRulePkIds(ctx, fk, currentFkIds, rule)
+			if err != nil {
+				return nil, err
+			}
+			if len(currentFkIds) == 0 {
+				continue
+			}
+		}
 		slog.Debug("FkIds", fk.ForeignTableName, currentFkIds)
 		isVisited := true
 		if tablePks, ok := tablePksByTable[fk.ForeignTableName]; ok {
-			if _, ok := tablePks.Filters[fk.ForeignColumnName]; !ok {
-				tablePks.Filters[fk.ForeignColumnName] = make(map[string]bool, len(currentFkIds))
+			if _, ok := tablePks.Filters[foreignColumnName]; !ok {
+				tablePks.Filters[foreignColumnName] = make(map[string]bool, len(currentFkIds))
 			}
 			for fkId := range currentFkIds {
-				if _, ok := tablePks.Filters[fk.ForeignColumnName][fkId]; !ok {
+				if _, ok := tablePks.Filters[foreignColumnName][fkId]; !ok {
 					isVisited = false
-					tablePks.Filters[fk.ForeignColumnName][fkId] = true
+					tablePks.Filters[foreignColumnName][fkId] = true
 				}
 
 			}
@@ -164,7 +419,7 @@ func (d *DumpService) getFksIds(
 			isVisited = false
 			tablePksByTable[fk.ForeignTableName] = &schemas.Table{
 				Name:    fk.ForeignTableName,
-				Filters: map[string]schemas.Pks{fk.ForeignColumnName: currentFkIds},
+				Filters: map[string]schemas.Pks{foreignColumnName: currentFkIds},
 				Fks:     make(map[string]*schemas.Table, 0),
 			}
 		}
@@ -175,7 +430,7 @@ func (d *DumpService) getFksIds(
 		if !isVisited {
 			newTable := config.Table{
 				Name:    fk.ForeignTableName,
-				Filters: []config.Filter{{Name: fk.ForeignColumnName, Value: buildStringFromSet(currentFkIds)}},
+				Filters: []config.Filter{{Name: foreignColumnName, Values: repositories.PksToValues(currentFkIds)}},
 			}
 			resultTables = append(resultTables, newTable)
 		}
@@ -183,6 +438,63 @@ func (d *DumpService) getFksIds(
 	return resultTables, nil
 }
 
+// Get pk ids of foreign table rows which are referenced by fk ids and match rule where predicate.
+// For incoming fk with max children per parent only first rows per referenced row are kept.
+// Return pk column name and pk ids
+func (d *DumpService) getRulePkIds(
+	ctx context.Context,
+	fk db.Fk,
+	fkIds map[string]bool,
+	rule config.Rule,
+) (string, map[string]bool, error) {
+	pkColumnName, err := d.getPkColumnName(ctx, fk.ForeignTableName)
+	if err != nil {
+		return "", nil, err
+	}
+	table := config.Table{
+		Name:    fk.ForeignTableName,
+		Filters: []config.Filter{{Name: fk.ForeignColumnName, Values: repositories.PksToValues(fkIds)}},
+		Where:   rule.Where,
+	}
+	var pkIdRows []map[string]any
+	if isChildrenLimited(rule, fk) {
+		orderBy := rule.OrderBy
+		if orderBy == "" {
+			orderBy = pkColumnName
+		}
+		pkIdRows, err = d.repo.GetLimitedPerParentPkIdRows(
+			ctx, d.c.Settings.SchemaName, table, pkColumnName, fk.ForeignColumnName, orderBy, rule.MaxChildrenPerParent,
+		)
+	} else {
+		pkIdRows, err = d.repo.GetPkIdRows(ctx, d.c.Settings.SchemaName, table, pkColumnName)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return pkColumnName, d.createIdsSet(pkIdRows, pkColumnName), nil
+}
+
+// Get pk column name with cache
+func (d *DumpService) getPkColumnName(ctx context.Context, tableName string) (string, error) {
+	d.cacheMu.Lock()
+	pkColumnName, ok := d.pkColumnNames[tableName]
+	d.cacheMu.Unlock()
+	if ok {
+		return pkColumnName, nil
+	}
+	pkColumnName, err := d.repo.GetPKColumnName(ctx, d.c.Settings.SchemaName, tableName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("table %s has no primary key. Set base_table to start from view", tableName)
+	}
+	if err != nil {
+		return "", err
+	}
+	d.cacheMu.Lock()
+	d.pkColumnNames[tableName] = pkColumnName
+	d.cacheMu.Unlock()
+	return pkColumnName, nil
+}
+
 // Create set of ids
 func (d *DumpService) createIdsSet(idsRows []map[string]any, columnName string) map[string]bool {
 	currentPkIds := make(map[string]bool)
@@ -169,3 +169,430 @@ func TestCollectTableFkIdsCycles(t *testing.T) {
 		t.Errorf("wrong err: %v, expected %v", err, nil)
 	}
 }
+
+func TestCollectTableFkIdsWithRules(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+
+	orderItemsTable := &schemas.Table{
+		Name:    "order_items",
+		Filters: map[string]schemas.Pks{"order_id": {"1": true, "3": true}},
+		Fks:     map[string]*schemas.Table{ordersTable.Name: ordersTable},
+	}
+	testC := *c
+	testC.Settings.Rules = []config.Rule{
+		{Table: ordersTable.Name, Constraint: "order_items_order_id_fkey", Direction: constants.INCOMING},
+	}
+	expected := tablePksByTableT{
+		userTable.Name:               userTable,
+		ordersTable.Name:             ordersTable,
+		userPaymentMethodsTable.Name: userPaymentMethodsTable,
+		orderItemsTable.Name:         orderItemsTable,
+	}
+	dumpService := New(&testC, repos)
+	actual, err := dumpService.collectTableFkIds(ctx)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+}
+
+func TestCollectTableFkIdsWithWhereRule(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+	c := &config.Config{
+		Settings: config.Settings{
+			SchemaName: "alpha",
+			Tables:     []config.Table{{Name: "users", Filters: []config.Filter{{Name: "id", Value: "4"}}}},
+			Direction:  constants.OUTGOING,
+			Rules: []config.Rule{
+				{Table: "users", ForeignTable: "orders", Direction: constants.INCOMING, Where: "status = 'completed'"},
+			},
+		},
+	}
+
+	userTable := &schemas.Table{
+		Name:    "users",
+		Filters: map[string]schemas.Pks{"id": {"4": true}},
+		Fks:     map[string]*schemas.Table{},
+	}
+	ordersTable := &schemas.Table{
+		Name:    "orders",
+		Filters: map[string]schemas.Pks{"id": {"5": true, "7": true}},
+		Fks:     map[string]*schemas.Table{userTable.Name: userTable},
+	}
+	expected := tablePksByTableT{
+		userTable.Name:   userTable,
+		ordersTable.Name: ordersTable,
+	}
+	dumpService := New(c, repos)
+	actual, err := dumpService.collectTableFkIds(ctx)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+}
+
+func TestCollectTableFkIdsWithMaxChildrenPerParent(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+	c := &config.Config{
+		Settings: config.Settings{
+			SchemaName: "alpha",
+			Tables:     []config.Table{{Name: "users", Filters: []config.Filter{{Name: "id", Value: "1, 4"}}}},
+			Direction:  constants.OUTGOING,
+			Rules: []config.Rule{
+				{
+					Table:                "users",
+					ForeignTable:         "orders",
+					Direction:            constants.INCOMING,
+					MaxChildrenPerParent: 1,
+					OrderBy:              "id DESC",
+				},
+			},
+		},
+	}
+
+	userTable := &schemas.Table{
+		Name:    "users",
+		Filters: map[string]schemas.Pks{"id": {"1": true, "4": true}},
+		Fks:     map[string]*schemas.Table{},
+	}
+	ordersTable := &schemas.Table{
+		Name:    "orders",
+		Filters: map[string]schemas.Pks{"id": {"2": true, "7": true}},
+		Fks:     map[string]*schemas.Table{userTable.Name: userTable},
+	}
+	expected := tablePksByTableT{
+		userTable.Name:   userTable,
+		ordersTable.Name: ordersTable,
+	}
+	dumpService := New(c, repos)
+	actual, err := dumpService.collectTableFkIds(ctx)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+}
+
+func TestCollectTableFkIdsWithNullifyNullableFks(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+
+	userPaymentMethodsTable := &schemas.Table{
+		Name:    "user_payment_methods",
+		Filters: map[string]schemas.Pks{"id": {"1": true, "2": true, "3": true}},
+		Fks:     map[string]*schemas.Table{userTable.Name: userTable},
+		NullFks: map[string]schemas.NullFk{"order_id": {ForeignTableName: "orders", ForeignColumnName: "id"}},
+	}
+	testC := *c
+	testC.Settings.NullifyNullableFks = true
+	expected := tablePksByTableT{
+		userTable.Name:               userTable,
+		userPaymentMethodsTable.Name: userPaymentMethodsTable,
+	}
+	dumpService := New(&testC, repos)
+	actual, err := dumpService.collectTableFkIds(ctx)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+}
+
+func TestCollectTableFkIdsWithMaxDepth(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+
+	testC := *c
+	testC.Settings.IncludeIncomingTables = []string{userTable.Name}
+	testC.Settings.MaxDepth = 1
+	expected := tablePksByTableT{
+		userTable.Name:               userTable,
+		ordersTable.Name:             ordersTable,
+		userPaymentMethodsTable.Name: userPaymentMethodsTable,
+	}
+	dumpService := New(&testC, repos)
+	actual, err := dumpService.collectTableFkIds(ctx)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+}
+
+func TestCollectTableFkIdsWithExcludedTables(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+
+	userPaymentMethodsTable := &schemas.Table{
+		Name:    "user_payment_methods",
+		Filters: map[string]schemas.Pks{"id": {"1": true, "2": true, "3": true}},
+		Fks:     map[string]*schemas.Table{userTable.Name: userTable},
+		NullFks: map[string]schemas.NullFk{"order_id": {ForeignTableName: "orders", ForeignColumnName: "id"}},
+	}
+	testC := *c
+	testC.Settings.ExcludeTables = []string{"ord*"}
+	testC.Settings.ExcludedReference = constants.NULLIFY
+	expected := tablePksByTableT{
+		userTable.Name:               userTable,
+		userPaymentMethodsTable.Name: userPaymentMethodsTable,
+	}
+	dumpService := New(&testC, repos)
+	actual, err := dumpService.collectTableFkIds(ctx)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+
+	testC.Settings.ExcludedReference = constants.FAIL
+	dumpService = New(&testC, repos)
+	_, err = dumpService.collectTableFkIds(ctx)
+	if err == nil {
+		t.Errorf("expected err for reference to excluded table")
+	}
+}
+
+func TestCollectTableFkIdsWithFullTables(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+
+	userTable := &schemas.Table{
+		Name:    "users",
+		Filters: map[string]schemas.Pks{},
+		Fks:     map[string]*schemas.Table{},
+		IsFull:  true,
+	}
+	ordersTable := &schemas.Table{
+		Name:    "orders",
+		Filters: map[string]schemas.Pks{"id": {"1": true, "3": true}},
+		Fks:     map[string]*schemas.Table{userTable.Name: userTable},
+	}
+	userPaymentMethodsTable := &schemas.Table{
+		Name:    "user_payment_methods",
+		Filters: map[string]schemas.Pks{"id": {"1": true, "2": true, "3": true}},
+		Fks: map[string]*schemas.Table{
+			userTable.Name: userTable, ordersTable.Name: ordersTable,
+		},
+	}
+	testC := *c
+	testC.Settings.FullTables = []string{"users"}
+	expected := tablePksByTableT{
+		userTable.Name:               userTable,
+		ordersTable.Name:             ordersTable,
+		userPaymentMethodsTable.Name: userPaymentMethodsTable,
+	}
+	dumpService := New(&testC, repos)
+	actual, err := dumpService.collectTableFkIds(ctx)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+}
+
+func TestCollectTableFkIdsWithView(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+	c := &config.Config{
+		Settings: config.Settings{
+			SchemaName: "alpha",
+			Tables: []config.Table{
+				{Name: "suspended_users_v", BaseTable: "users", KeyColumn: "user_id"},
+			},
+			Direction: constants.OUTGOING,
+		},
+	}
+
+	userTable := &schemas.Table{
+		Name:    "users",
+		Filters: map[string]schemas.Pks{"id": {"5": true}},
+		Fks:     map[string]*schemas.Table{},
+	}
+	expected := tablePksByTableT{
+		userTable.Name: userTable,
+	}
+	dumpService := New(c, repos)
+	actual, err := dumpService.collectTableFkIds(ctx)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+
+	c.Settings.Tables = []config.Table{{Name: "suspended_users_v"}}
+	_, err = New(c, repos).collectTableFkIds(ctx)
+	if err == nil {
+		t.Errorf("expected err for view without base table")
+	}
+}
+
+func TestCollectTableFkIdsWithOutgoingWhereRule(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+	type TestData struct {
+		name     string
+		rule     config.Rule
+		expected map[string]schemas.NullFk
+		isError  bool
+	}
+	tests := []TestData{
+		{
+			name: "test nullable fk is nullified",
+			rule: config.Rule{
+				Table: "user_payment_methods", ForeignTable: "orders", Direction: constants.OUTGOING, Where: "status = 'pending'",
+			},
+			expected: map[string]schemas.NullFk{"order_id": {ForeignTableName: "orders", ForeignColumnName: "id"}},
+		},
+		{
+			name: "test not nullable fk fails",
+			rule: config.Rule{
+				Table: "user_payment_methods", ForeignTable: "users", Direction: constants.OUTGOING, Where: "status = 'active'",
+			},
+			isError: true,
+		},
+	}
+	for _, test := range tests {
+		c := &config.Config{
+			Settings: config.Settings{
+				SchemaName: "alpha",
+				Tables:     []config.Table{{Name: "user_payment_methods", Filters: []config.Filter{{Name: "id", Value: "1, 3"}}}},
+				Direction:  constants.OUTGOING,
+				Rules:      []config.Rule{test.rule},
+			},
+		}
+		actual, err := New(c, repos).collectTableFkIds(ctx)
+		if (err != nil) != test.isError {
+			t.Errorf("%s: wrong err %v", test.name, err)
+		}
+		if test.isError {
+			continue
+		}
+		if diff := cmp.Diff(test.expected, actual["user_payment_methods"].NullFks); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", test.name, diff)
+		}
+		if _, ok := actual["orders"]; ok {
+			t.Errorf("%s: filtered-out orders are dumped", test.name)
+		}
+	}
+}
+
+func TestInitWithOutgoingWhereRule(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+	// Rule table is not reached from starting table, so error is reported before traversal only
+	c := &config.Config{
+		Settings: config.Settings{
+			SchemaName: "alpha",
+			Tables:     []config.Table{{Name: "users", Filters: []config.Filter{{Name: "id", Value: "1"}}}},
+			Direction:  constants.OUTGOING,
+			Rules: []config.Rule{
+				{Table: "order_items", ForeignTable: "orders", Direction: constants.OUTGOING, Where: "status = 'pending'"},
+			},
+		},
+	}
+	_, err := New(c, repos).collectTableFkIds(ctx)
+	if err == nil {
+		t.Errorf("wrong err: %v, expected error", err)
+	}
+}
+
+func TestCollectTableFkIdsWithFollowOneToOne(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+	type TestData struct {
+		name           string
+		followOneToOne bool
+		expected       map[string]schemas.Pks
+	}
+	tests := []TestData{
+		{name: "test one-to-one is not followed by default"},
+		{
+			name:           "test one-to-one is followed",
+			followOneToOne: true,
+			expected:       map[string]schemas.Pks{"order_item_id": {"1": true, "4": true}},
+		},
+	}
+	for _, test := range tests {
+		c := &config.Config{
+			Settings: config.Settings{
+				SchemaName:     "alpha",
+				Tables:         []config.Table{{Name: "order_items", Filters: []config.Filter{{Name: "id", Value: "1, 2, 4"}}}},
+				Direction:      constants.OUTGOING,
+				FollowOneToOne: test.followOneToOne,
+			},
+		}
+		actual, err := New(c, repos).collectTables(ctx, c.Settings.Tables)
+		if err != nil {
+			t.Fatalf("%s: wrong err %v", test.name, err)
+		}
+		var filters map[string]schemas.Pks
+		if reviews, ok := actual["order_item_reviews"]; ok {
+			filters = reviews.Filters
+		}
+		if diff := cmp.Diff(test.expected, filters); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", test.name, diff)
+		}
+	}
+}
+
+func TestCollectTableFkIdsWithFullTableReferences(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+	c := &config.Config{
+		Settings: config.Settings{
+			SchemaName: "alpha",
+			Tables:     []config.Table{{Name: "user_payment_methods", Filters: []config.Filter{{Name: "id", Value: "1"}}}},
+			Direction:  constants.OUTGOING,
+			FullTables: []string{"orders"},
+		},
+	}
+	actual, err := New(c, repos).collectTableFkIds(ctx)
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	// Users referenced by all orders are dumped
+	expected := map[string]schemas.Pks{"id": {"1": true, "2": true, "3": true, "4": true}}
+	if diff := cmp.Diff(expected, actual["users"].Filters); diff != "" {
+		t.Errorf("users mismatch (-want +got):\n%s", diff)
+	}
+	if !actual["orders"].IsFull {
+		t.Errorf("orders is not full table")
+	}
+}
@@ -0,0 +1,95 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+	"github.com/t1m4/db_part_dump/internal/services/relations"
+)
+
+// Fk of dump rows which references rows removed by default filters of foreign table
+type filteredReference struct {
+	tableName string
+	fk        db.Fk
+	count     int64
+}
+
+// Combine default filters of every table with AND
+func newDefaultFilters(tableSettings []config.TableSettings) map[string]string {
+	defaultFilters := make(map[string]string)
+	for _, settings := range tableSettings {
+		for _, filter := range settings.DefaultFilters {
+			filter = fmt.Sprintf("(%s)", filter)
+			if current, ok := defaultFilters[settings.Name]; ok {
+				filter = fmt.Sprintf("%s AND %s", current, filter)
+			}
+			defaultFilters[settings.Name] = filter
+		}
+	}
+	return defaultFilters
+}
+
+// Report fks of dump rows which reference rows removed by default filters.
+// Such references are not satisfied in dump
+func (d *DumpService) reportFilteredReferences(ctx context.Context, tablePksByTable tablePksByTableT) error {
+	references, err := d.getFilteredReferences(ctx, tablePksByTable)
+	if err != nil {
+		return err
+	}
+	for _, reference := range references {
+		slog.Warn(
+			"Rows reference rows removed by default filters",
+			"table", reference.tableName,
+			"column", reference.fk.ColumnName,
+			"foreign_table", reference.fk.ForeignTableName,
+			"count", reference.count,
+		)
+	}
+	return nil
+}
+
+// Get fks of dump rows which reference rows removed by default filters
+func (d *DumpService) getFilteredReferences(
+	ctx context.Context,
+	tablePksByTable tablePksByTableT,
+) ([]filteredReference, error) {
+	defaultFilters := newDefaultFilters(d.c.Settings.TableSettings)
+	references := make([]filteredReference, 0)
+	if len(defaultFilters) == 0 {
+		return references, nil
+	}
+	tableNames := make([]string, 0, len(tablePksByTable))
+	for tableName := range tablePksByTable {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+	for _, tableName := range tableNames {
+		table := tablePksByTable[tableName]
+		fks, err := d.repo.GetFKs(ctx, constants.OUTGOING, d.c.Settings.SchemaName, tableName, false)
+		if err != nil {
+			return nil, err
+		}
+		fks = mergeFks(fks, relations.Fks(d.relations, d.c.Settings.SchemaName, tableName, false))
+		for _, fk := range fks {
+			if _, ok := defaultFilters[fk.ForeignTableName]; !ok {
+				continue
+			}
+			if _, ok := table.NullFks[fk.ColumnName]; ok {
+				continue
+			}
+			count, err := d.repo.GetFilteredReferencesCount(ctx, d.c.Settings.SchemaName, table, fk)
+			if err != nil {
+				return nil, err
+			}
+			if count != 0 {
+				references = append(references, filteredReference{tableName: tableName, fk: fk, count: count})
+			}
+		}
+	}
+	return references, nil
+}
@@ -0,0 +1,56 @@
+package dump
+
+import (
+	"context"
+	"testing"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/repositories"
+	"github.com/t1m4/db_part_dump/internal/testutil"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewDefaultFilters(t *testing.T) {
+	tableSettings := []config.TableSettings{
+		{Name: "users", DefaultFilters: []string{"deleted_at IS NULL", "tenant_id = 42"}},
+		{Name: "orders", MaxRows: 10},
+		{Name: "orders", DefaultFilters: []string{"status <> 'draft'"}},
+	}
+	expected := map[string]string{
+		"users":  "(deleted_at IS NULL) AND (tenant_id = 42)",
+		"orders": "(status <> 'draft')",
+	}
+	actual := newDefaultFilters(tableSettings)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetFilteredReferences(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+
+	testC := *c
+	testC.Settings.TableSettings = []config.TableSettings{
+		{Name: "orders", DefaultFilters: []string{"status <> 'completed'"}},
+	}
+	dumpService := New(&testC, repos)
+	tablePksByTable, err := dumpService.collectTableFkIds(ctx)
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	references, err := dumpService.getFilteredReferences(ctx, tablePksByTable)
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+	if len(references) != 1 {
+		t.Fatalf("got %d references, expected %d", len(references), 1)
+	}
+	reference := references[0]
+	if reference.tableName != "user_payment_methods" || reference.fk.ColumnName != "order_id" || reference.count != 2 {
+		t.Errorf("wrong reference %+v", reference)
+	}
+}
@@ -0,0 +1,168 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+	"github.com/t1m4/db_part_dump/internal/schemas"
+	"github.com/t1m4/db_part_dump/internal/services/relations"
+)
+
+// Build order expression to choose rows to keep
+func buildSelectionOrder(tableSettings config.TableSettings, pkColumnName string) string {
+	orderBy := tableSettings.OrderBy
+	if orderBy == "" {
+		orderBy = pkColumnName
+	}
+	switch tableSettings.Selection {
+	case constants.NEWEST:
+		return fmt.Sprintf("%s DESC, %s", orderBy, pkColumnName)
+	case constants.OLDEST:
+		return fmt.Sprintf("%s ASC, %s", orderBy, pkColumnName)
+	default:
+		return buildRandomOrder(pkColumnName, tableSettings.Seed)
+	}
+}
+
+// Hash of pk with seed gives the same random order on every run
+func buildRandomOrder(pkColumnName string, seed int64) string {
+	return fmt.Sprintf("md5(%s::text || '%d'), %s", pkColumnName, seed, pkColumnName)
+}
+
+// Trim tables to max rows and remove rows which parents were removed
+func (d *DumpService) limitTables(ctx context.Context, tablePksByTable tablePksByTableT) error {
+	changedTables := make(map[string]bool)
+	for _, tableSettings := range d.c.Settings.TableSettings {
+		table, ok := tablePksByTable[tableSettings.Name]
+		if !ok || table.IsFull || tableSettings.MaxRows == 0 {
+			continue
+		}
+		isChanged, err := d.limitTable(ctx, table, tableSettings)
+		if err != nil {
+			return err
+		}
+		if isChanged {
+			changedTables[table.Name] = true
+		}
+	}
+	return d.repairTables(ctx, tablePksByTable, changedTables)
+}
+
+// Keep only first max rows of table in selection order
+func (d *DumpService) limitTable(ctx context.Context, table *schemas.Table, tableSettings config.TableSettings) (bool, error) {
+	pkColumnName, err := d.getPkColumnName(ctx, table.Name)
+	if err != nil {
+		return false, err
+	}
+	orderBy := buildSelectionOrder(tableSettings, pkColumnName)
+	pkIdRows, err := d.repo.GetLimitedPkIdRows(
+		ctx, d.c.Settings.SchemaName, table, pkColumnName, orderBy, tableSettings.MaxRows+1,
+	)
+	if err != nil {
+		return false, err
+	}
+	if len(pkIdRows) <= tableSettings.MaxRows {
+		return false, nil
+	}
+	pkIds := d.createIdsSet(pkIdRows[:tableSettings.MaxRows], pkColumnName)
+	slog.Info("Table is trimmed", "table", table.Name, "max_rows", tableSettings.MaxRows)
+	table.Filters = map[string]schemas.Pks{pkColumnName: pkIds}
+	return true, nil
+}
+
+// Remove rows referencing removed rows of changed tables until all fks are satisfied
+func (d *DumpService) repairTables(
+	ctx context.Context,
+	tablePksByTable tablePksByTableT,
+	changedTables map[string]bool,
+) error {
+	tableNames := make([]string, 0, len(tablePksByTable))
+	for tableName := range tablePksByTable {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+	for len(changedTables) != 0 {
+		nextChangedTables := make(map[string]bool)
+		for _, tableName := range tableNames {
+			table := tablePksByTable[tableName]
+			if table.IsFull {
+				continue
+			}
+			fks, err := d.getParentFks(ctx, table, tablePksByTable, changedTables)
+			if err != nil {
+				return err
+			}
+			if len(fks) == 0 {
+				continue
+			}
+			isChanged, err := d.repairTable(ctx, table, fks, tablePksByTable)
+			if err != nil {
+				return err
+			}
+			if isChanged {
+				nextChangedTables[tableName] = true
+			}
+		}
+		changedTables = nextChangedTables
+	}
+	return nil
+}
+
+// Get outgoing fks of table to changed tables in dump
+func (d *DumpService) getParentFks(
+	ctx context.Context,
+	table *schemas.Table,
+	tablePksByTable tablePksByTableT,
+	changedTables map[string]bool,
+) ([]db.Fk, error) {
+	fks, err := d.repo.GetFKs(ctx, constants.OUTGOING, d.c.Settings.SchemaName, table.Name, false)
+	if err != nil {
+		return nil, err
+	}
+	fks = mergeFks(fks, relations.Fks(d.relations, d.c.Settings.SchemaName, table.Name, false))
+	result := make([]db.Fk, 0, len(fks))
+	for _, fk := range fks {
+		if _, ok := table.NullFks[fk.ColumnName]; ok {
+			continue
+		}
+		if _, ok := tablePksByTable[fk.ForeignTableName]; !ok || !changedTables[fk.ForeignTableName] {
+			continue
+		}
+		result = append(result, fk)
+	}
+	return result, nil
+}
+
+// Keep only table rows which reference existing parent rows
+func (d *DumpService) repairTable(
+	ctx context.Context,
+	table *schemas.Table,
+	fks []db.Fk,
+	tablePksByTable tablePksByTableT,
+) (bool, error) {
+	pkColumnName, err := d.getPkColumnName(ctx, table.Name)
+	if err != nil {
+		return false, err
+	}
+	currentRows, err := d.repo.GetColumnValues(ctx, d.c.Settings.SchemaName, table, pkColumnName)
+	if err != nil {
+		return false, err
+	}
+	pkIdRows, err := d.repo.GetPkIdRowsWithParents(
+		ctx, d.c.Settings.SchemaName, table, pkColumnName, fks, tablePksByTable,
+	)
+	if err != nil {
+		return false, err
+	}
+	if len(pkIdRows) == len(currentRows) {
+		return false, nil
+	}
+	slog.Info("Rows with removed parents are removed", "table", table.Name, "count", len(currentRows)-len(pkIdRows))
+	table.Filters = map[string]schemas.Pks{pkColumnName: d.createIdsSet(pkIdRows, pkColumnName)}
+	return true, nil
+}
@@ -0,0 +1,78 @@
+package dump
+
+import (
+	"context"
+	"testing"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/repositories"
+	"github.com/t1m4/db_part_dump/internal/schemas"
+	"github.com/t1m4/db_part_dump/internal/testutil"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildSelectionOrder(t *testing.T) {
+	type TestData struct {
+		name          string
+		tableSettings config.TableSettings
+		expected      string
+	}
+	tests := []TestData{
+		{
+			name:          "test random",
+			tableSettings: config.TableSettings{Selection: constants.RANDOM, Seed: 42},
+			expected:      "md5(id::text || '42'), id",
+		},
+		{
+			name:          "test newest",
+			tableSettings: config.TableSettings{Selection: constants.NEWEST, OrderBy: "created_at"},
+			expected:      "created_at DESC, id",
+		},
+		{
+			name:          "test oldest without order by",
+			tableSettings: config.TableSettings{Selection: constants.OLDEST},
+			expected:      "id ASC, id",
+		},
+	}
+	for _, test := range tests {
+		actual := buildSelectionOrder(test.tableSettings, "id")
+		if actual != test.expected {
+			t.Errorf("%s: got %s, expected %s", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestLimitTables(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+
+	testC := *c
+	testC.Settings.TableSettings = []config.TableSettings{
+		{Name: "users", MaxRows: 1, Selection: constants.OLDEST},
+	}
+	dumpService := New(&testC, repos)
+	tablePksByTable, err := dumpService.collectTableFkIds(ctx)
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	err = dumpService.limitTables(ctx, tablePksByTable)
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+	expected := map[string]map[string]schemas.Pks{
+		"users":                {"id": {"1": true}},
+		"orders":               {"id": {"1": true}},
+		"user_payment_methods": {"id": {"1": true, "2": true}},
+	}
+	actual := make(map[string]map[string]schemas.Pks, len(tablePksByTable))
+	for tableName, table := range tablePksByTable {
+		actual[tableName] = table.Filters
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
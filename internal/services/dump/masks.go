@@ -0,0 +1,19 @@
+package dump
+
+import (
+	"context"
+
+	"github.com/t1m4/db_part_dump/internal/services/masking"
+)
+
+// Check masks against columns of schema before rows are collected
+func (d *DumpService) checkMasks(ctx context.Context) error {
+	if len(d.c.Settings.Masks) == 0 {
+		return nil
+	}
+	columns, err := d.repo.GetColumns(ctx, d.c.Settings.SchemaName)
+	if err != nil {
+		return err
+	}
+	return masking.CheckMasks(d.c.Settings.Masks, columns)
+}
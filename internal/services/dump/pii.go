@@ -0,0 +1,44 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/t1m4/db_part_dump/internal/schemas"
+	"github.com/t1m4/db_part_dump/internal/services/pii"
+)
+
+// Count of sampled rows of every table for PII scan by default
+const defaultPiiSampleSize = 100
+
+// Report columns of dumped tables with likely PII which have no masks.
+// Fail when masking is required, so unmasked PII never reaches output
+func (d *DumpService) checkPii(ctx context.Context, tablePks []*schemas.Table) error {
+	if !d.c.Settings.ScanPii && !d.c.Settings.RequireMasking {
+		return nil
+	}
+	sampleSize := d.c.Settings.PiiSampleSize
+	if sampleSize == 0 {
+		sampleSize = defaultPiiSampleSize
+	}
+	findings, err := pii.Scan(ctx, d.repo, d.c.Settings.SchemaName, tablePks, sampleSize)
+	if err != nil {
+		return err
+	}
+	uncovered := pii.Uncovered(findings, d.c.Settings.Masks)
+	slog.Info("PII scan", "columns", len(findings), "not_masked", len(uncovered))
+	for _, finding := range uncovered {
+		slog.Warn(
+			"Column with PII is not masked",
+			"table", finding.Table,
+			"column", finding.Column,
+			"kind", finding.Kind,
+			"source", finding.Source,
+		)
+	}
+	if d.c.Settings.RequireMasking && len(uncovered) != 0 {
+		return fmt.Errorf("%d columns with PII are not masked", len(uncovered))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package dump
+
+import (
+	"context"
+	"testing"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/repositories"
+	"github.com/t1m4/db_part_dump/internal/schemas"
+	"github.com/t1m4/db_part_dump/internal/testutil"
+)
+
+func TestCheckPii(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+	tablePks := []*schemas.Table{{Name: "users", Filters: map[string]schemas.Pks{"id": {"1": true, "2": true}}}}
+	type TestData struct {
+		name    string
+		masks   []config.Mask
+		isError bool
+	}
+	tests := []TestData{
+		{name: "test not masked email", isError: true},
+		{
+			name:  "test masked email",
+			masks: []config.Mask{{Table: "users", Column: "email", Method: constants.MASK_EMAIL}},
+		},
+	}
+	for _, test := range tests {
+		c := &config.Config{
+			Settings: config.Settings{SchemaName: "alpha", Masks: test.masks, RequireMasking: true},
+		}
+		err := New(c, repos).checkPii(ctx, tablePks)
+		if (err != nil) != test.isError {
+			t.Errorf("%s: wrong err %v", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+)
+
+type rulesByTableT map[string][]config.Rule
+
+func newRulesByTable(rules []config.Rule) rulesByTableT {
+	rulesByTable := make(rulesByTableT, len(rules))
+	for _, rule := range rules {
+		rulesByTable[rule.Table] = append(rulesByTable[rule.Table], rule)
+	}
+	return rulesByTable
+}
+
+// Check that table has rule which can allow incoming fks
+func hasIncomingRule(rules []config.Rule) bool {
+	for _, rule := range rules {
+		if rule.Direction != constants.OUTGOING && rule.Direction != constants.NONE {
+			return true
+		}
+	}
+	return false
+}
+
+// Check that fk can be followed with direction.
+// In owned direction only incoming fks with ON DELETE CASCADE are followed
+func isFkAllowed(direction string, fk db.Fk) bool {
+	switch direction {
+	case constants.BOTH:
+		return true
+	case constants.NONE:
+		return false
+	case constants.OWNED:
+		return fk.Direction == constants.OUTGOING || fk.OnDelete == constants.CASCADE
+	default:
+		return direction == fk.Direction
+	}
+}
+
+// Find the most specific rule for fk. Rule for constraint or foreign table has priority over rule for whole table
+func findRule(rules []config.Rule, fk db.Fk) (config.Rule, bool) {
+	var tableRule config.Rule
+	isTableRuleFound := false
+	for _, rule := range rules {
+		if rule.Constraint == "" && rule.ForeignTable == "" {
+			tableRule = rule
+			isTableRuleFound = true
+			continue
+		}
+		if rule.Constraint != "" && rule.Constraint != fk.ConstraintName {
+			continue
+		}
+		if rule.ForeignTable != "" && rule.ForeignTable != fk.ForeignTableName {
+			continue
+		}
+		return rule, true
+	}
+	return tableRule, isTableRuleFound
+}
+
+// Filter table fks by rules. Fks without rule are followed by default direction.
+// Incoming one-to-one fks without rule are followed when isFollowOneToOne
+func filterFks(fks []db.Fk, rules []config.Rule, defaultDirection string, isFollowOneToOne bool) []db.Fk {
+	result := make([]db.Fk, 0, len(fks))
+	for _, fk := range fks {
+		rule, ok := findRule(rules, fk)
+		if ok && !isFkAllowed(rule.Direction, fk) {
+			continue
+		}
+		isOneToOne := isFollowOneToOne && fk.Direction == constants.INCOMING && fk.IsOneToOne
+		if !ok && !isFkAllowed(defaultDirection, fk) && !isOneToOne {
+			continue
+		}
+		result = append(result, fk)
+	}
+	return result
+}
+
+// Check that nullable outgoing fk should not be followed and set to NULL instead.
+// Rule for fk has priority over global setting
+func isNullifyFk(rules []config.Rule, fk db.Fk, isNullifyNullable bool) bool {
+	if fk.Direction != constants.OUTGOING || !fk.IsNullable {
+		return false
+	}
+	if rule, ok := findRule(rules, fk); ok && rule.Nullify {
+		return true
+	}
+	return isNullifyNullable
+}
+
+// Check that referencing rows of incoming fk are limited per referenced row
+func isChildrenLimited(rule config.Rule, fk db.Fk) bool {
+	return fk.Direction == constants.INCOMING && rule.MaxChildrenPerParent > 0
+}
+
+// Check that rule reaches only part of foreign table rows referenced by fk
+func isRuleRowsFiltered(rule config.Rule, fk db.Fk) bool {
+	return rule.Where != "" || isChildrenLimited(rule, fk)
+}
+
+// Check before traversal that rows of outgoing fks filtered by rule where can be set to NULL
+func (d *DumpService) checkRules(ctx context.Context) error {
+	for tableName, rules := range d.rulesByTable {
+		fks, err := d.getFks(ctx, tableName, d.tablePatterns.isIncludeIncoming(tableName))
+		if err != nil {
+			return err
+		}
+		for _, fk := range fks {
+			if fk.Direction != constants.OUTGOING || fk.IsNullable {
+				continue
+			}
+			if rule, ok := findRule(rules, fk); ok && isRuleRowsFiltered(rule, fk) {
+				return fmt.Errorf("rule where of outgoing fk %s.%s requires nullable column", tableName, fk.ColumnName)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package dump
+
+import (
+	"testing"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFilterFks(t *testing.T) {
+	type TestData struct {
+		name             string
+		fks              []db.Fk
+		rules            []config.Rule
+		defaultDirection string
+		isFollowOneToOne bool
+		expected         []db.Fk
+	}
+	usersFk := db.Fk{
+		ColumnName:        "user_id",
+		ForeignTableName:  "users",
+		ForeignColumnName: "id",
+		Direction:         constants.OUTGOING,
+		ConstraintName:    "orders_user_id_fkey",
+	}
+	orderItemsFk := db.Fk{
+		ColumnName:        "id",
+		ForeignTableName:  "order_items",
+		ForeignColumnName: "order_id",
+		Direction:         constants.INCOMING,
+		ConstraintName:    "order_items_order_id_fkey",
+		OnDelete:          constants.CASCADE,
+	}
+	auditLogFk := db.Fk{
+		ColumnName:        "id",
+		ForeignTableName:  "audit_log",
+		ForeignColumnName: "order_id",
+		Direction:         constants.INCOMING,
+		ConstraintName:    "audit_log_order_id_fkey",
+		OnDelete:          "n",
+	}
+	preferencesFk := db.Fk{
+		ColumnName:        "id",
+		ForeignTableName:  "order_preferences",
+		ForeignColumnName: "order_id",
+		Direction:         constants.INCOMING,
+		ConstraintName:    "order_preferences_order_id_fkey",
+		IsOneToOne:        true,
+	}
+	fks := []db.Fk{usersFk, orderItemsFk, auditLogFk}
+	tests := []TestData{
+		{
+			name:             "test without rules",
+			fks:              fks,
+			defaultDirection: constants.OUTGOING,
+			expected:         []db.Fk{usersFk},
+		},
+		{
+			name:             "test without rules and include incoming",
+			fks:              fks,
+			defaultDirection: constants.BOTH,
+			expected:         fks,
+		},
+		{
+			name:             "test without rules and owned",
+			fks:              fks,
+			defaultDirection: constants.OWNED,
+			expected:         []db.Fk{usersFk, orderItemsFk},
+		},
+		{
+			name:             "test without rules and one-to-one",
+			fks:              append(fks, preferencesFk),
+			defaultDirection: constants.OUTGOING,
+			isFollowOneToOne: true,
+			expected:         []db.Fk{usersFk, preferencesFk},
+		},
+		{
+			name:             "test one-to-one with table rule",
+			fks:              append(fks, preferencesFk),
+			rules:            []config.Rule{{Table: "orders", Direction: constants.OUTGOING}},
+			defaultDirection: constants.OUTGOING,
+			isFollowOneToOne: true,
+			expected:         []db.Fk{usersFk},
+		},
+		{
+			name:             "test constraint rule",
+			fks:              fks,
+			rules:            []config.Rule{{Table: "orders", Constraint: "order_items_order_id_fkey", Direction: constants.INCOMING}},
+			defaultDirection: constants.OUTGOING,
+			expected:         []db.Fk{usersFk, orderItemsFk},
+		},
+		{
+			name: "test foreign table rule has priority over table rule",
+			fks:  fks,
+			rules: []config.Rule{
+				{Table: "orders", Direction: constants.BOTH},
+				{Table: "orders", ForeignTable: "audit_log", Direction: constants.NONE},
+			},
+			defaultDirection: constants.OUTGOING,
+			expected:         []db.Fk{usersFk, orderItemsFk},
+		},
+		{
+			name:             "test table rule none",
+			fks:              fks,
+			rules:            []config.Rule{{Table: "orders", Direction: constants.NONE}},
+			defaultDirection: constants.BOTH,
+			expected:         []db.Fk{},
+		},
+	}
+	for _, test := range tests {
+		actual := filterFks(test.fks, test.rules, test.defaultDirection, test.isFollowOneToOne)
+		if diff := cmp.Diff(test.expected, actual); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", test.name, diff)
+		}
+	}
+}
+
+func TestIsNullifyFk(t *testing.T) {
+	type TestData struct {
+		name              string
+		fk                db.Fk
+		rules             []config.Rule
+		isNullifyNullable bool
+		expected          bool
+	}
+	nullableFk := db.Fk{
+		ColumnName:        "order_id",
+		ForeignTableName:  "orders",
+		ForeignColumnName: "id",
+		Direction:         constants.OUTGOING,
+		IsNullable:        true,
+	}
+	notNullFk := db.Fk{
+		ColumnName:        "user_id",
+		ForeignTableName:  "users",
+		ForeignColumnName: "id",
+		Direction:         constants.OUTGOING,
+	}
+	tests := []TestData{
+		{name: "test global", fk: nullableFk, isNullifyNullable: true, expected: true},
+		{name: "test not nullable", fk: notNullFk, isNullifyNullable: true, expected: false},
+		{name: "test disabled", fk: nullableFk, expected: false},
+		{
+			name:     "test rule",
+			fk:       nullableFk,
+			rules:    []config.Rule{{Table: "user_payment_methods", ForeignTable: "orders", Direction: constants.OUTGOING, Nullify: true}},
+			expected: true,
+		},
+	}
+	for _, test := range tests {
+		actual := isNullifyFk(test.rules, test.fk, test.isNullifyNullable)
+		if actual != test.expected {
+			t.Errorf("%s: got %v, expected %v", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestIsChildrenLimited(t *testing.T) {
+	type TestData struct {
+		name     string
+		rule     config.Rule
+		fk       db.Fk
+		expected bool
+	}
+	incomingFk := db.Fk{ColumnName: "id", ForeignTableName: "orders", ForeignColumnName: "user_id", Direction: constants.INCOMING}
+	outgoingFk := db.Fk{ColumnName: "user_id", ForeignTableName: "users", ForeignColumnName: "id", Direction: constants.OUTGOING}
+	tests := []TestData{
+		{name: "test incoming", rule: config.Rule{MaxChildrenPerParent: 5}, fk: incomingFk, expected: true},
+		{name: "test outgoing", rule: config.Rule{MaxChildrenPerParent: 5}, fk: outgoingFk, expected: false},
+		{name: "test unlimited", rule: config.Rule{}, fk: incomingFk, expected: false},
+	}
+	for _, test := range tests {
+		actual := isChildrenLimited(test.rule, test.fk)
+		if actual != test.expected {
+			t.Errorf("%s: got %v, expected %v", test.name, actual, test.expected)
+		}
+	}
+}
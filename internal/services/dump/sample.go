@@ -0,0 +1,61 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+)
+
+// Table sample percent is increased because filters and where are applied after sampling
+const sampleOversampling = 2
+
+// Build percent of table pages or rows for TABLESAMPLE to get about rows count
+func buildSamplePercent(rows int, estimate int64) float64 {
+	if estimate <= 0 {
+		return 100
+	}
+	return min(float64(rows)*100*sampleOversampling/float64(estimate), 100)
+}
+
+// Build TABLESAMPLE clause with reproducible seed
+func buildTableSample(method string, percent float64, seed int64) string {
+	return fmt.Sprintf(
+		" TABLESAMPLE %s (%s) REPEATABLE (%d)",
+		strings.ToUpper(method),
+		strconv.FormatFloat(percent, 'f', -1, 64),
+		seed,
+	)
+}
+
+// Get pk ids of sample of starting table rows matching filters and where
+func (d *DumpService) getSamplePkIdRows(
+	ctx context.Context,
+	table config.Table,
+	pkColumnName string,
+) ([]map[string]any, error) {
+	sample := table.Sample
+	tableSample := ""
+	orderBy := buildRandomOrder(pkColumnName, sample.Seed)
+	if sample.Method == constants.BERNOULLI || sample.Method == constants.SYSTEM {
+		estimates, err := d.repo.GetTableRowEstimates(ctx, d.c.Settings.SchemaName)
+		if err != nil {
+			return nil, err
+		}
+		estimate := estimates[table.Name]
+		if estimate > 0 {
+			percent := buildSamplePercent(sample.Rows, estimate)
+			tableSample = buildTableSample(sample.Method, percent, sample.Seed)
+			orderBy = pkColumnName
+		} else {
+			slog.Warn("Table has no rows estimate, random sample is used", "table", table.Name)
+		}
+	}
+	return d.repo.GetSamplePkIdRows(
+		ctx, d.c.Settings.SchemaName, table, pkColumnName, tableSample, orderBy, sample.Rows,
+	)
+}
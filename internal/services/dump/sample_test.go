@@ -0,0 +1,72 @@
+package dump
+
+import (
+	"context"
+	"testing"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/repositories"
+	"github.com/t1m4/db_part_dump/internal/testutil"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildSamplePercent(t *testing.T) {
+	type TestData struct {
+		name     string
+		rows     int
+		estimate int64
+		expected float64
+	}
+	tests := []TestData{
+		{name: "test oversampling", rows: 500, estimate: 100000, expected: 1},
+		{name: "test small table", rows: 500, estimate: 100, expected: 100},
+		{name: "test without estimate", rows: 500, estimate: -1, expected: 100},
+	}
+	for _, test := range tests {
+		actual := buildSamplePercent(test.rows, test.estimate)
+		if actual != test.expected {
+			t.Errorf("%s: got %v, expected %v", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestBuildTableSample(t *testing.T) {
+	actual := buildTableSample(constants.BERNOULLI, 0.5, 42)
+	expected := " TABLESAMPLE BERNOULLI (0.5) REPEATABLE (42)"
+	if actual != expected {
+		t.Errorf("got %s, expected %s", actual, expected)
+	}
+}
+
+func TestCollectTableFkIdsWithSample(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+	c := &config.Config{
+		Settings: config.Settings{
+			SchemaName: "alpha",
+			Tables: []config.Table{
+				{Name: "orders", Sample: config.Sample{Rows: 3, Method: constants.RANDOM, Seed: 42}},
+			},
+			Direction: constants.OUTGOING,
+		},
+	}
+
+	first, err := New(c, repos).collectTableFkIds(ctx)
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	if count := len(first["orders"].Filters["id"]); count != 3 {
+		t.Errorf("got %d sampled orders, expected %d", count, 3)
+	}
+	second, err := New(c, repos).collectTableFkIds(ctx)
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	if diff := cmp.Diff(first, second); diff != "" {
+		t.Errorf("sample is not reproducible (-want +got):\n%s", diff)
+	}
+}
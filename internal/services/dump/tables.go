@@ -0,0 +1,139 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+	"github.com/t1m4/db_part_dump/internal/patterns"
+	"github.com/t1m4/db_part_dump/internal/schemas"
+)
+
+// Table selection rules for traversal
+type tablePatterns struct {
+	schemaName      string
+	includeIncoming *patterns.Patterns
+	include         *patterns.Patterns
+	exclude         *patterns.Patterns
+	stop            *patterns.Patterns
+	full            *patterns.Patterns
+}
+
+func newTablePatterns(settings config.Settings) (*tablePatterns, error) {
+	includeIncoming, err := patterns.Compile(settings.IncludeIncomingTables)
+	if err != nil {
+		return nil, err
+	}
+	include, err := patterns.Compile(settings.IncludeTables)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := patterns.Compile(settings.ExcludeTables)
+	if err != nil {
+		return nil, err
+	}
+	stop, err := patterns.Compile(settings.StopTables)
+	if err != nil {
+		return nil, err
+	}
+	full, err := patterns.Compile(settings.FullTables)
+	if err != nil {
+		return nil, err
+	}
+	return &tablePatterns{
+		schemaName:      settings.SchemaName,
+		includeIncoming: includeIncoming,
+		include:         include,
+		exclude:         exclude,
+		stop:            stop,
+		full:            full,
+	}, nil
+}
+
+func (p *tablePatterns) isIncludeIncoming(tableName string) bool {
+	return p.includeIncoming.Match(p.schemaName, tableName)
+}
+
+// Table is not included or excluded from traversal
+func (p *tablePatterns) isExcluded(tableName string) bool {
+	if !p.include.IsEmpty() && !p.include.Match(p.schemaName, tableName) {
+		return true
+	}
+	return p.exclude.Match(p.schemaName, tableName)
+}
+
+// Table rows are dumped but fks are not followed
+func (p *tablePatterns) isStop(tableName string) bool {
+	return p.stop.Match(p.schemaName, tableName)
+}
+
+// Remove incoming fks from excluded tables.
+// Outgoing fks to excluded tables are kept to handle references in getFksIds
+func (p *tablePatterns) filterExcludedFks(fks []db.Fk) []db.Fk {
+	result := make([]db.Fk, 0, len(fks))
+	for _, fk := range fks {
+		if fk.Direction == constants.INCOMING && p.isExcluded(fk.ForeignTableName) {
+			continue
+		}
+		result = append(result, fk)
+	}
+	return result
+}
+
+// Handle reference to excluded table by failing or setting NULL to nullable fk
+func handleExcludedReference(table *schemas.Table, fk db.Fk, excludedReference string) error {
+	if excludedReference == constants.NULLIFY && fk.IsNullable {
+		addNullFk(table, fk)
+		return nil
+	}
+	return fmt.Errorf(
+		"table %s references excluded table %s by column %s",
+		table.Name,
+		fk.ForeignTableName,
+		fk.ColumnName,
+	)
+}
+
+// Add full tables as visited tables without filters, so traversal never expands them.
+// Tables are chosen by full_tables patterns and by estimated rows count.
+// Return full tables to follow their outgoing fks, so rows referenced by full tables are dumped too
+func (d *DumpService) initFullTables(ctx context.Context, tablePksByTable tablePksByTableT) ([]config.Table, error) {
+	fullTables := make([]config.Table, 0)
+	maxRows := d.c.Settings.FullTablesMaxRows
+	if d.tablePatterns.full.IsEmpty() && maxRows == 0 {
+		return fullTables, nil
+	}
+	estimates, err := d.repo.GetTableRowEstimates(ctx, d.c.Settings.SchemaName)
+	if err != nil {
+		return nil, err
+	}
+	tableNames := make([]string, 0, len(estimates))
+	for tableName := range estimates {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+	for _, tableName := range tableNames {
+		estimate := estimates[tableName]
+		// Not analyzed tables have estimate -1, or 0 before PostgreSQL 14, so they are not small
+		isSmall := maxRows != 0 && estimate > 0 && estimate <= maxRows
+		if !isSmall && !d.tablePatterns.full.Match(d.c.Settings.SchemaName, tableName) {
+			continue
+		}
+		if d.tablePatterns.isExcluded(tableName) {
+			continue
+		}
+		slog.Info("Full table", "table", tableName, "estimated_rows", estimate)
+		tablePksByTable[tableName] = &schemas.Table{
+			Name:    tableName,
+			Filters: make(map[string]schemas.Pks),
+			Fks:     make(map[string]*schemas.Table),
+			IsFull:  true,
+		}
+		fullTables = append(fullTables, config.Table{Name: tableName})
+	}
+	return fullTables, nil
+}
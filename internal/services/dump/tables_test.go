@@ -0,0 +1,70 @@
+package dump
+
+import (
+	"testing"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+	"github.com/t1m4/db_part_dump/internal/schemas"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTablePatterns(t *testing.T) {
+	p, err := newTablePatterns(config.Settings{
+		SchemaName:            "alpha",
+		IncludeIncomingTables: []string{"user*"},
+		ExcludeTables:         []string{"*_audit", "django_session"},
+		StopTables:            []string{"re:^alpha\\.coupons$"},
+	})
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	if !p.isIncludeIncoming("users") || p.isIncludeIncoming("orders") {
+		t.Errorf("wrong include incoming match")
+	}
+	if !p.isExcluded("orders_audit") || !p.isExcluded("django_session") || p.isExcluded("orders") {
+		t.Errorf("wrong excluded match")
+	}
+	if !p.isStop("coupons") || p.isStop("order_coupons") {
+		t.Errorf("wrong stop match")
+	}
+
+	ordersFk := db.Fk{ColumnName: "order_id", ForeignTableName: "orders", Direction: constants.OUTGOING}
+	auditFk := db.Fk{ColumnName: "id", ForeignTableName: "orders_audit", Direction: constants.INCOMING}
+	actual := p.filterExcludedFks([]db.Fk{ordersFk, auditFk})
+	if diff := cmp.Diff([]db.Fk{ordersFk}, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTablePatternsInclude(t *testing.T) {
+	p, err := newTablePatterns(config.Settings{SchemaName: "alpha", IncludeTables: []string{"users", "orders"}})
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	if p.isExcluded("users") || !p.isExcluded("order_items") {
+		t.Errorf("wrong include match")
+	}
+}
+
+func TestHandleExcludedReference(t *testing.T) {
+	nullableFk := db.Fk{ColumnName: "order_id", ForeignTableName: "orders", ForeignColumnName: "id", IsNullable: true}
+	notNullFk := db.Fk{ColumnName: "user_id", ForeignTableName: "users", ForeignColumnName: "id"}
+
+	table := &schemas.Table{Name: "user_payment_methods"}
+	if err := handleExcludedReference(table, nullableFk, constants.NULLIFY); err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+	expected := map[string]schemas.NullFk{"order_id": {ForeignTableName: "orders", ForeignColumnName: "id"}}
+	if diff := cmp.Diff(expected, table.NullFks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if err := handleExcludedReference(table, notNullFk, constants.NULLIFY); err == nil {
+		t.Errorf("expected err for not nullable fk")
+	}
+	if err := handleExcludedReference(table, nullableFk, constants.FAIL); err == nil {
+		t.Errorf("expected err for fail excluded reference")
+	}
+}
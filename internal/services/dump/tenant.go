@@ -0,0 +1,55 @@
+package dump
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/db"
+)
+
+// Build starting tables filtered by tenant for every table with tenant column and pk.
+// Tables without pk can not be dumped and excluded tables must not be dumped, they are skipped.
+// Incoming fks of tenant rows lead to shared tables and then to rows of other tenants, so only outgoing fks are followed
+func buildTenantTables(columns []db.Column, tenantColumn string, tenant string, tablePatterns *tablePatterns) []config.Table {
+	hasPk := make(map[string]bool)
+	for _, column := range columns {
+		if column.IsPrimaryKey {
+			hasPk[column.TableName] = true
+		}
+	}
+	tables := make([]config.Table, 0)
+	for _, column := range columns {
+		if column.ColumnName != tenantColumn {
+			continue
+		}
+		if !hasPk[column.TableName] {
+			slog.Warn("Table with tenant column has no primary key, it is skipped", "table", column.TableName)
+			continue
+		}
+		if tablePatterns.isExcluded(column.TableName) {
+			continue
+		}
+		tables = append(tables, config.Table{
+			Name:         column.TableName,
+			Filters:      []config.Filter{{Name: tenantColumn, Values: []string{tenant}}},
+			OutgoingOnly: true,
+		})
+	}
+	return tables
+}
+
+// Get starting tables of tenant. Outgoing fks from them lead to shared tables
+func (d *DumpService) getTenantTables(ctx context.Context) ([]config.Table, error) {
+	err := d.init(ctx)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := d.repo.GetColumns(ctx, d.c.Settings.SchemaName)
+	if err != nil {
+		return nil, err
+	}
+	tables := buildTenantTables(columns, d.c.Settings.TenantColumn, d.c.Settings.Tenant, d.tablePatterns)
+	slog.Info("Tenant tables", "count", len(tables), "tenant", d.c.Settings.Tenant)
+	return tables, nil
+}
@@ -0,0 +1,123 @@
+package dump
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+	"github.com/t1m4/db_part_dump/internal/repositories"
+	"github.com/t1m4/db_part_dump/internal/schemas"
+	"github.com/t1m4/db_part_dump/internal/testutil"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildTenantTables(t *testing.T) {
+	columns := []db.Column{
+		{TableName: "users", ColumnName: "id", IsPrimaryKey: true},
+		{TableName: "users", ColumnName: "tenant_id"},
+		{TableName: "countries", ColumnName: "id", IsPrimaryKey: true},
+		{TableName: "events", ColumnName: "tenant_id"},
+		{TableName: "audit_logs", ColumnName: "id", IsPrimaryKey: true},
+		{TableName: "audit_logs", ColumnName: "tenant_id"},
+	}
+	tablePatterns, err := newTablePatterns(config.Settings{ExcludeTables: []string{"audit_*"}})
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	expected := []config.Table{
+		{Name: "users", Filters: []config.Filter{{Name: "tenant_id", Values: []string{"42"}}}, OutgoingOnly: true},
+	}
+	actual := buildTenantTables(columns, "tenant_id", "42", tablePatterns)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCollectTenantTables(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+	c := &config.Config{
+		Settings: config.Settings{
+			SchemaName:   "alpha",
+			Direction:    constants.OUTGOING,
+			TenantColumn: "user_id",
+			Tenant:       "4",
+		},
+	}
+
+	dumpService := New(c, repos)
+	tables, err := dumpService.getTenantTables(ctx)
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	tablePksByTable, err := dumpService.collectTables(ctx, tables)
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	expectedNames := []string{"orders", "user_addresses", "user_payment_methods", "user_preferences", "users"}
+	actualNames := make([]string, 0, len(tablePksByTable))
+	for tableName := range tablePksByTable {
+		actualNames = append(actualNames, tableName)
+	}
+	sort.Strings(actualNames)
+	if diff := cmp.Diff(expectedNames, actualNames); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	expectedOrders := map[string]schemas.Pks{"id": {"5": true, "6": true, "7": true}}
+	if diff := cmp.Diff(expectedOrders, tablePksByTable["orders"].Filters); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCollectTenantTablesWithConfiguredTable(t *testing.T) {
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+	c := &config.Config{
+		Settings: config.Settings{
+			SchemaName:    "alpha",
+			Direction:     constants.OUTGOING,
+			TenantColumn:  "user_id",
+			Tenant:        "4",
+			ExcludeTables: []string{"user_preferences"},
+			Rules:         []config.Rule{{Table: "orders", ForeignTable: "order_items", Direction: constants.INCOMING}},
+			Tables:        []config.Table{{Name: "orders", Filters: []config.Filter{{Name: "id", Value: "1"}}}},
+		},
+	}
+
+	dumpService := New(c, repos)
+	tables, err := dumpService.getTenantTables(ctx)
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	tablePksByTable, err := dumpService.collectTables(ctx, append(c.Settings.Tables, tables...))
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	// Excluded tables are not tenant tables
+	expectedNames := []string{"order_items", "orders", "user_addresses", "user_payment_methods", "users"}
+	actualNames := make([]string, 0, len(tablePksByTable))
+	for tableName := range tablePksByTable {
+		actualNames = append(actualNames, tableName)
+	}
+	sort.Strings(actualNames)
+	if diff := cmp.Diff(expectedNames, actualNames); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	expectedOrders := map[string]schemas.Pks{"id": {"1": true, "5": true, "6": true, "7": true}}
+	if diff := cmp.Diff(expectedOrders, tablePksByTable["orders"].Filters); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	// Incoming fks are followed from configured table only
+	expectedOrderItems := map[string]schemas.Pks{"order_id": {"1": true}}
+	if diff := cmp.Diff(expectedOrderItems, tablePksByTable["order_items"].Filters); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
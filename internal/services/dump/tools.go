@@ -3,17 +3,12 @@ package dump
 import (
 	"fmt"
 	"log"
+	"maps"
 	"reflect"
-	"strings"
-)
 
-func buildStringFromSet(fkIds map[string]bool) string {
-	result := make([]string, 0)
-	for fkId := range fkIds {
-		result = append(result, fmt.Sprintf("%v", fkId))
-	}
-	return strings.Join(result, ", ")
-}
+	"github.com/t1m4/db_part_dump/internal/db"
+	"github.com/t1m4/db_part_dump/internal/schemas"
+)
 
 func AnyToString(value any) string {
 	switch v := value.(type) {
@@ -26,3 +21,38 @@ func AnyToString(value any) string {
 	}
 	return ""
 }
+
+// Append fks which are not exist yet
+func mergeFks(fks []db.Fk, newFks []db.Fk) []db.Fk {
+	existing := make(map[db.Fk]bool, len(fks))
+	for _, fk := range fks {
+		existing[fk] = true
+	}
+	for _, fk := range newFks {
+		if _, ok := existing[fk]; ok {
+			continue
+		}
+		existing[fk] = true
+		fks = append(fks, fk)
+	}
+	return fks
+}
+
+// Add fk which is not followed and set to NULL on export
+func addNullFk(table *schemas.Table, fk db.Fk) {
+	if table.NullFks == nil {
+		table.NullFks = make(map[string]schemas.NullFk)
+	}
+	table.NullFks[fk.ColumnName] = schemas.NullFk{
+		ForeignTableName:  fk.ForeignTableName,
+		ForeignColumnName: fk.ForeignColumnName,
+	}
+}
+
+// Add pk ids to filter of table
+func addPks(table *schemas.Table, columnName string, pks schemas.Pks) {
+	if table.Filters[columnName] == nil {
+		table.Filters[columnName] = make(schemas.Pks, len(pks))
+	}
+	maps.Copy(table.Filters[columnName], pks)
+}
@@ -6,32 +6,75 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
 	"github.com/t1m4/db_part_dump/internal/repositories"
 	"github.com/t1m4/db_part_dump/internal/schemas"
+	"github.com/t1m4/db_part_dump/internal/services/masking"
+	"github.com/t1m4/db_part_dump/internal/services/relations"
+	"github.com/t1m4/db_part_dump/transform"
 )
 
+// Column types shifted by date shift
+var dateTypes = map[string]bool{
+	"date":        true,
+	"timestamp":   true,
+	"timestamptz": true,
+}
+
 type Exporter interface {
 	ExportToFile(ctx context.Context, tablePks []*schemas.Table) error
+	ExportToPath(ctx context.Context, tablePks []*schemas.Table, output string) error
+	SetRelations(relations []config.Relation)
+	CheckSecret() error
 }
 
 type PostgresqlExporter struct {
-	c    *config.Config
-	repo repositories.RepositoriesI
+	c            *config.Config
+	repo         repositories.RepositoriesI
+	masker       *masking.Masker
+	transformers []transform.RowTransformer
+	relations    []config.Relation // Virtual relations from config and inferred ones
 }
 
 func New(c *config.Config, repo *repositories.Repositories) Exporter {
-	return &PostgresqlExporter{c: c, repo: repo}
+	secret := []byte(os.Getenv(pseudonymSecretEnv(c)))
+	return &PostgresqlExporter{
+		c:            c,
+		repo:         repo,
+		masker:       masking.New(c.Settings.Masks, secret),
+		transformers: transform.Registered(),
+		relations:    c.Settings.Relations,
+	}
+}
+
+// Set virtual relations resolved by dump, including inferred ones
+func (d *PostgresqlExporter) SetRelations(relations []config.Relation) {
+	d.relations = relations
 }
+
+// Check that keyed masks have secret
+func (d *PostgresqlExporter) CheckSecret() error {
+	if d.masker.HasKeyedMasks() && len(d.masker.Secret()) == 0 {
+		return fmt.Errorf("hash, email, name, range and pseudonymize masks require secret in environment variable %s", pseudonymSecretEnv(d.c))
+	}
+	return nil
+}
+
+// Create temporary file in directory of output, so it can be renamed to output when export succeeds
 func (d *PostgresqlExporter) createFile(filename string) (*os.File, error) {
-	if filename == "" {
-		timestamp := time.Now().Format("20060102_150405")
-		filename = fmt.Sprintf("backup_%s.sql", timestamp)
+	file, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return nil, err
 	}
-	file, err := os.Create(filename)
+	err = file.Chmod(0o644)
 	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
 		return nil, err
 	}
 	return file, nil
@@ -39,21 +82,198 @@ func (d *PostgresqlExporter) createFile(filename string) (*os.File, error) {
 
 // Export to file
 func (d *PostgresqlExporter) ExportToFile(ctx context.Context, tablePks []*schemas.Table) error {
-	file, err := d.createFile(d.c.Settings.Output)
-	defer file.Close()
+	return d.ExportToPath(ctx, tablePks, d.c.Settings.Output)
+}
+
+// Export to file with output path. Output file appears only when export succeeds
+func (d *PostgresqlExporter) ExportToPath(ctx context.Context, tablePks []*schemas.Table, output string) error {
+	err := d.CheckSecret()
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		timestamp := time.Now().Format("20060102_150405")
+		output = fmt.Sprintf("backup_%s.sql", timestamp)
+	}
+	file, err := d.createFile(output)
 	if err != nil {
 		return err
 	}
-	writer := bufio.NewWriter(file)
+	err = d.export(ctx, tablePks, bufio.NewWriter(file))
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	err = os.Rename(file.Name(), output)
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	slog.Info(fmt.Sprintf("Export to %s finished", output))
+	return nil
+}
 
+// Write rows of tables to writer
+func (d *PostgresqlExporter) export(ctx context.Context, tablePks []*schemas.Table, writer *bufio.Writer) error {
+	masker, err := d.pseudonymMasker(ctx, tablePks)
+	if err != nil {
+		return err
+	}
+	var fksByTable map[string][]db.Fk
+	var dropped droppedRows
+	if len(d.transformers) != 0 {
+		fksByTable, err = d.getOutgoingFks(ctx, tablePks)
+		if err != nil {
+			return err
+		}
+		dropped = newDroppedRows(fksByTable)
+	}
+
+	tablesByName := make(map[string]*schemas.Table, len(tablePks))
+	for _, tablePk := range tablePks {
+		tablesByName[tablePk.Name] = tablePk
+	}
 	for _, tablePk := range tablePks {
 		slog.Debug("")
 		slog.Debug("ExportSQL", tablePk.Name, tablePk.Filters)
-		err := d.repo.GetRows(ctx, d.c.Settings.SchemaName, tablePk, writer)
+		transforms := make([]repositories.RowTransform, 0)
+		if len(tablePk.NullFks) != 0 {
+			transform, err := d.nullFksTransform(ctx, tablePk, tablesByName)
+			if err != nil {
+				return err
+			}
+			transforms = append(transforms, transform)
+		}
+		if len(tablePk.DateShifts) != 0 {
+			transforms = append(transforms, dateShiftTransform(tablePk))
+		}
+		if len(d.transformers) != 0 {
+			rowTransform := transformersTransform(tablePk.Name, d.transformers, fksByTable[tablePk.Name], dropped)
+			replay, err := d.cascadeSelfReferences(ctx, tablePk, fksByTable[tablePk.Name], dropped, append(transforms, rowTransform))
+			if err != nil {
+				return err
+			}
+			if replay != nil {
+				// Recorded values already include previous transforms
+				transforms = []repositories.RowTransform{replay}
+			} else {
+				transforms = append(transforms, rowTransform)
+			}
+		}
+		if transform := masker.Transform(tablePk.Name); transform != nil {
+			transforms = append(transforms, transform)
+		}
+		err := d.repo.GetRows(ctx, d.c.Settings.SchemaName, tablePk, writer, transforms...)
 		if err != nil {
 			return err
 		}
 	}
-	slog.Info(fmt.Sprintf("Export to %s finished", file.Name()))
 	return nil
 }
+
+// Create transform which sets NULL to not followed fk columns referencing rows which are not in dump
+func (d *PostgresqlExporter) nullFksTransform(
+	ctx context.Context,
+	tablePk *schemas.Table,
+	tablesByName map[string]*schemas.Table,
+) (repositories.RowTransform, error) {
+	existingValues := make(map[string]map[string]bool, len(tablePk.NullFks))
+	for columnName, nullFk := range tablePk.NullFks {
+		existingValues[columnName] = make(map[string]bool)
+		foreignTable, ok := tablesByName[nullFk.ForeignTableName]
+		if !ok {
+			continue
+		}
+		rows, err := d.repo.GetColumnValues(ctx, d.c.Settings.SchemaName, foreignTable, nullFk.ForeignColumnName)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			existingValues[columnName][repositories.AnyToPsqlString(row[nullFk.ForeignColumnName])] = true
+		}
+	}
+	return func(columns []db.Column, values []any) (bool, error) {
+		for i, column := range columns {
+			existing, ok := existingValues[column.ColumnName]
+			if !ok || values[i] == nil {
+				continue
+			}
+			if _, ok := existing[repositories.AnyToPsqlString(values[i])]; !ok {
+				values[i] = nil
+			}
+		}
+		return true, nil
+	}, nil
+}
+
+// Create transform which shifts date and timestamp columns by days of row pk
+func dateShiftTransform(tablePk *schemas.Table) repositories.RowTransform {
+	return func(columns []db.Column, values []any) (bool, error) {
+		days := 0
+		for i, column := range columns {
+			if column.ColumnName == tablePk.ShiftColumn {
+				days = tablePk.DateShifts[repositories.AnyToPsqlString(values[i])]
+			}
+		}
+		if days == 0 {
+			return true, nil
+		}
+		for i, column := range columns {
+			if !dateTypes[column.DataType] {
+				continue
+			}
+			if value, ok := values[i].(time.Time); ok {
+				values[i] = value.AddDate(0, 0, days)
+			}
+		}
+		return true, nil
+	}
+}
+
+func pseudonymSecretEnv(c *config.Config) string {
+	if c.Settings.PseudonymSecretEnv == "" {
+		return config.DefaultPseudonymSecretEnv
+	}
+	return c.Settings.PseudonymSecretEnv
+}
+
+// Create masker with pseudonymize masks propagated along fks of dumped tables,
+// so referencing and referenced columns get equal pseudonyms
+func (d *PostgresqlExporter) pseudonymMasker(ctx context.Context, tablePks []*schemas.Table) (*masking.Masker, error) {
+	if !d.masker.HasPseudonyms() {
+		return d.masker, nil
+	}
+	fksByTable, err := d.getOutgoingFks(ctx, tablePks)
+	if err != nil {
+		return nil, err
+	}
+	masks, err := masking.PropagatePseudonyms(d.c.Settings.Masks, fksByTable)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := d.repo.GetColumns(ctx, d.c.Settings.SchemaName)
+	if err != nil {
+		return nil, err
+	}
+	masker := masking.New(masks, d.masker.Secret())
+	masker.SetIntegerTypes(masking.IntegerTypes(masks, fksByTable, columns))
+	return masker, nil
+}
+
+// Get outgoing fks and relations of dumped tables
+func (d *PostgresqlExporter) getOutgoingFks(ctx context.Context, tablePks []*schemas.Table) (map[string][]db.Fk, error) {
+	fksByTable := make(map[string][]db.Fk, len(tablePks))
+	for _, tablePk := range tablePks {
+		fks, err := d.repo.GetFKs(ctx, constants.OUTGOING, d.c.Settings.SchemaName, tablePk.Name, false)
+		if err != nil {
+			return nil, err
+		}
+		relationFks := relations.Fks(d.relations, d.c.Settings.SchemaName, tablePk.Name, false)
+		fksByTable[tablePk.Name] = append(fks, relationFks...)
+	}
+	return fksByTable, nil
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/t1m4/db_part_dump/config"
 	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
 	"github.com/t1m4/db_part_dump/internal/repositories"
 	"github.com/t1m4/db_part_dump/internal/schemas"
+	"github.com/t1m4/db_part_dump/internal/services/masking"
 	"github.com/t1m4/db_part_dump/internal/testutil"
 
 	"github.com/google/go-cmp/cmp"
@@ -88,7 +91,7 @@ func TestPostgresqlExporter(t *testing.T) {
 	repos := repositories.New(db)
 	ctx := context.Background()
 
-	exporter := PostgresqlExporter{c, repos}
+	exporter := PostgresqlExporter{c, repos, masking.New(c.Settings.Masks, nil), nil, nil}
 	tablePks := []*schemas.Table{userTable, ordersTable, userPaymentMethodsTable}
 	err := exporter.ExportToFile(ctx, tablePks)
 	if err != nil {
@@ -100,3 +103,146 @@ func TestPostgresqlExporter(t *testing.T) {
 	}
 	_ = os.Remove(c.Settings.Output)
 }
+
+var expectedWithNullFks = `-- Data for Name: alpha.orders; Type: TABLE DATA;
+ALTER TABLE alpha.orders DISABLE TRIGGER ALL;
+COPY alpha.orders ("id", "user_id", "order_date", "total_amount", "status") FROM stdin;
+1	1	2025-01-01T10:00:00Z	99.99	completed
+\.
+ALTER TABLE alpha.orders ENABLE TRIGGER ALL;
+
+
+-- Data for Name: alpha.user_payment_methods; Type: TABLE DATA;
+ALTER TABLE alpha.user_payment_methods DISABLE TRIGGER ALL;
+COPY alpha.user_payment_methods ("id", "user_id", "order_id", "payment_type", "card_number", "expiry_date", "is_default", "created_at") FROM stdin;
+1	1	1	credit_card	4111111111111111	2025-12-01T00:00:00Z	t	2025-01-01T10:00:00Z
+2	1	\N	paypal	\N	\N	f	2025-01-02T11:00:00Z
+3	2	\N	credit_card	4222222222222222	2024-10-01T00:00:00Z	t	2025-01-03T12:00:00Z
+\.
+ALTER TABLE alpha.user_payment_methods ENABLE TRIGGER ALL;
+
+
+`
+
+func TestPostgresqlExporterWithNullFks(t *testing.T) {
+	ordersTable := &schemas.Table{
+		Name:    "orders",
+		Filters: map[string]schemas.Pks{"id": {"1": true}},
+	}
+	userPaymentMethodsTable := &schemas.Table{
+		Name:    "user_payment_methods",
+		Filters: map[string]schemas.Pks{"id": {"1": true, "2": true, "3": true}},
+		NullFks: map[string]schemas.NullFk{"order_id": {ForeignTableName: "orders", ForeignColumnName: "id"}},
+	}
+	c := &config.Config{
+		Settings: config.Settings{
+			Output:     "test_postgresql_null_fks.sql",
+			SchemaName: "alpha",
+		},
+	}
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+
+	exporter := PostgresqlExporter{c, repos, masking.New(c.Settings.Masks, nil), nil, nil}
+	err := exporter.ExportToFile(ctx, []*schemas.Table{ordersTable, userPaymentMethodsTable})
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+	actual := ReadFile(t, c.Settings.Output)
+	if diff := cmp.Diff(expectedWithNullFks, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	_ = os.Remove(c.Settings.Output)
+}
+
+var expectedWithMasks = `-- Data for Name: alpha.user_payment_methods; Type: TABLE DATA;
+ALTER TABLE alpha.user_payment_methods DISABLE TRIGGER ALL;
+COPY alpha.user_payment_methods ("id", "user_id", "order_id", "payment_type", "card_number", "expiry_date", "is_default", "created_at") FROM stdin;
+1	1	1	masked	************1111	2025-12-01T00:00:00Z	t	2025-01-01T10:00:00Z
+2	1	\N	masked	\N	\N	f	2025-01-02T11:00:00Z
+\.
+ALTER TABLE alpha.user_payment_methods ENABLE TRIGGER ALL;
+
+
+`
+
+func TestPostgresqlExporterWithMasks(t *testing.T) {
+	userPaymentMethodsTable := &schemas.Table{
+		Name:    "user_payment_methods",
+		Filters: map[string]schemas.Pks{"id": {"1": true, "2": true}},
+	}
+	c := &config.Config{
+		Settings: config.Settings{
+			Output:     "test_postgresql_masks.sql",
+			SchemaName: "alpha",
+			Masks: []config.Mask{
+				{Table: "user_payment_methods", Column: "card_number", Method: constants.MASK_PARTIAL},
+				{Table: "user_payment_methods", Column: "payment_type", Method: constants.MASK_CONSTANT, Value: "masked"},
+			},
+		},
+	}
+	db := testutil.CreateTestDb(t)
+	testutil.InsertTestData(t, db)
+	repos := repositories.New(db)
+	ctx := context.Background()
+
+	exporter := PostgresqlExporter{c, repos, masking.New(c.Settings.Masks, nil), nil, nil}
+	err := exporter.ExportToFile(ctx, []*schemas.Table{userPaymentMethodsTable})
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+	actual := ReadFile(t, c.Settings.Output)
+	if diff := cmp.Diff(expectedWithMasks, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	_ = os.Remove(c.Settings.Output)
+}
+
+func TestPostgresqlExporterWithoutSecret(t *testing.T) {
+	c := &config.Config{
+		Settings: config.Settings{
+			Output:     "test_postgresql_without_secret.sql",
+			SchemaName: "alpha",
+			Masks:      []config.Mask{{Table: "users", Column: "email", Method: constants.MASK_HASH}},
+		},
+	}
+	ctx := context.Background()
+
+	exporter := PostgresqlExporter{c, nil, masking.New(c.Settings.Masks, nil), nil, nil}
+	err := exporter.ExportToFile(ctx, []*schemas.Table{})
+	if err == nil {
+		t.Fatalf("wrong err: %v, expected error", err)
+	}
+	// Output is not created when secret is missing
+	if _, err := os.Stat(c.Settings.Output); !os.IsNotExist(err) {
+		t.Errorf("wrong err: %v, expected not exist error", err)
+		_ = os.Remove(c.Settings.Output)
+	}
+}
+
+func TestDateShiftTransform(t *testing.T) {
+	tablePk := &schemas.Table{Name: "orders", ShiftColumn: "id", DateShifts: schemas.DateShifts{"1": -10}}
+	columns := []db.Column{
+		{ColumnName: "id", DataType: "int4"},
+		{ColumnName: "order_date", DataType: "timestamp"},
+		{ColumnName: "status", DataType: "varchar"},
+	}
+	date := time.Date(2025, 1, 11, 10, 0, 0, 0, time.UTC)
+	values := []any{int64(1), date, "completed"}
+	isKept, err := dateShiftTransform(tablePk)(columns, values)
+	if !isKept || err != nil {
+		t.Errorf("wrong result %v, %v", isKept, err)
+	}
+	expected := []any{int64(1), time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC), "completed"}
+	if diff := cmp.Diff(expected, values); diff != "" {
+		t.Errorf("values mismatch (-want +got):\n%s", diff)
+	}
+	// Row without shift is not changed
+	values = []any{int64(2), date, "completed"}
+	_, _ = dateShiftTransform(tablePk)(columns, values)
+	if diff := cmp.Diff([]any{int64(2), date, "completed"}, values); diff != "" {
+		t.Errorf("values mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,217 @@
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"slices"
+
+	"github.com/t1m4/db_part_dump/internal/db"
+	"github.com/t1m4/db_part_dump/internal/repositories"
+	"github.com/t1m4/db_part_dump/internal/schemas"
+	"github.com/t1m4/db_part_dump/transform"
+)
+
+// Values of referenced columns of dropped rows by table and column name
+type droppedRows map[string]map[string]map[string]bool
+
+// Track only columns which are referenced by fks of dumped tables
+func newDroppedRows(fksByTable map[string][]db.Fk) droppedRows {
+	dropped := make(droppedRows)
+	for _, fks := range fksByTable {
+		for _, fk := range fks {
+			if _, ok := dropped[fk.ForeignTableName]; !ok {
+				dropped[fk.ForeignTableName] = make(map[string]map[string]bool)
+			}
+			dropped[fk.ForeignTableName][fk.ForeignColumnName] = make(map[string]bool)
+		}
+	}
+	return dropped
+}
+
+// Check if row references dropped row by one of fks
+func (r droppedRows) isReferencing(fks []db.Fk, columns []db.Column, values []any) bool {
+	for _, fk := range fks {
+		droppedValues := r[fk.ForeignTableName][fk.ForeignColumnName]
+		if len(droppedValues) == 0 {
+			continue
+		}
+		index := columnIndex(columns, fk.ColumnName)
+		if index == -1 || values[index] == nil {
+			continue
+		}
+		if droppedValues[repositories.AnyToPsqlString(values[index])] {
+			return true
+		}
+	}
+	return false
+}
+
+// Remember referenced column values of dropped row
+func (r droppedRows) add(tableName string, columns []db.Column, values []any) {
+	droppedColumns, ok := r[tableName]
+	if !ok {
+		return
+	}
+	for i, column := range columns {
+		droppedValues, ok := droppedColumns[column.ColumnName]
+		if !ok || values[i] == nil {
+			continue
+		}
+		droppedValues[repositories.AnyToPsqlString(values[i])] = true
+	}
+}
+
+// Create transform which runs registered transformers. Rows referencing dropped rows are dropped too.
+// Referenced tables are exported first, so drops cascade to all referencing rows except rows of fk cycles
+// between different tables. Self-referencing tables are cascaded before export by cascadeSelfReferences
+func transformersTransform(
+	tableName string,
+	transformers []transform.RowTransformer,
+	fks []db.Fk,
+	dropped droppedRows,
+) repositories.RowTransform {
+	var publicColumns []transform.Column
+	return func(columns []db.Column, values []any) (bool, error) {
+		if publicColumns == nil {
+			publicColumns = make([]transform.Column, len(columns))
+			for i, column := range columns {
+				publicColumns[i] = transform.Column{Name: column.ColumnName, DataType: column.DataType}
+			}
+		}
+		original := slices.Clone(values)
+		isKept := !dropped.isReferencing(fks, columns, values)
+		for _, transformer := range transformers {
+			if !isKept {
+				break
+			}
+			var err error
+			isKept, err = transformer.Transform(tableName, publicColumns, values)
+			if err != nil {
+				return false, err
+			}
+		}
+		if !isKept {
+			dropped.add(tableName, columns, original)
+		}
+		return isKept, nil
+	}
+}
+
+// Drop rows of self-referencing table which reference dropped rows of the same table.
+// Rows of one table come in arbitrary order, so transforms are run over all rows first
+// and drops are cascaded in memory. Returned transform replays decisions and values by row pk on export,
+// so transformers are called once per row. Nil transform is returned for table without self fks
+func (d *PostgresqlExporter) cascadeSelfReferences(
+	ctx context.Context,
+	tablePk *schemas.Table,
+	fks []db.Fk,
+	dropped droppedRows,
+	transforms []repositories.RowTransform,
+) (repositories.RowTransform, error) {
+	selfFks := make([]db.Fk, 0)
+	for _, fk := range fks {
+		if fk.ForeignTableName == tablePk.Name {
+			selfFks = append(selfFks, fk)
+		}
+	}
+	if len(selfFks) == 0 {
+		return nil, nil
+	}
+	pkColumnName, err := d.repo.GetPKColumnName(ctx, d.c.Settings.SchemaName, tablePk.Name)
+	if err != nil {
+		return nil, err
+	}
+	var rowColumns []db.Column
+	keptRows := make([][]any, 0)
+	transformedRows := make([][]any, 0)
+	collect := func(columns []db.Column, values []any) (bool, error) {
+		original := slices.Clone(values)
+		for _, transform := range transforms {
+			isKept, err := transform(columns, values)
+			if err != nil || !isKept {
+				return false, err
+			}
+		}
+		rowColumns = columns
+		keptRows = append(keptRows, original)
+		transformedRows = append(transformedRows, values)
+		return false, nil
+	}
+	err = d.repo.GetRows(ctx, d.c.Settings.SchemaName, tablePk, bufio.NewWriter(io.Discard), collect)
+	if err != nil {
+		return nil, err
+	}
+	isDropped := cascadeDrops(tablePk.Name, selfFks, rowColumns, keptRows, dropped)
+	pkIndex := columnIndex(rowColumns, pkColumnName)
+	rows := make(map[string][]any, len(keptRows))
+	for i, values := range keptRows {
+		if !isDropped[i] {
+			rows[repositories.AnyToPsqlString(values[pkIndex])] = transformedRows[i]
+		}
+	}
+	return replayTransform(pkColumnName, rows), nil
+}
+
+// Create transform which keeps rows recorded by pk and replaces their values with recorded ones
+func replayTransform(pkColumnName string, rows map[string][]any) repositories.RowTransform {
+	return func(columns []db.Column, values []any) (bool, error) {
+		index := columnIndex(columns, pkColumnName)
+		if index == -1 {
+			return false, nil
+		}
+		transformed, ok := rows[repositories.AnyToPsqlString(values[index])]
+		if !ok {
+			return false, nil
+		}
+		copy(values, transformed)
+		return true, nil
+	}
+}
+
+// Drop kept rows which reference dropped rows by self fks until no kept row references dropped row.
+// Return drop flags of kept rows
+func cascadeDrops(tableName string, selfFks []db.Fk, columns []db.Column, keptRows [][]any, dropped droppedRows) []bool {
+	// Kept rows by referenced column and value of self fk
+	children := make(map[string][]int)
+	for i, values := range keptRows {
+		for _, fk := range selfFks {
+			index := columnIndex(columns, fk.ColumnName)
+			if index == -1 || values[index] == nil {
+				continue
+			}
+			key := fk.ForeignColumnName + "\x00" + repositories.AnyToPsqlString(values[index])
+			children[key] = append(children[key], i)
+		}
+	}
+	queue := make([]string, 0)
+	for _, fk := range selfFks {
+		for value := range dropped[tableName][fk.ForeignColumnName] {
+			queue = append(queue, fk.ForeignColumnName+"\x00"+value)
+		}
+	}
+	isDropped := make([]bool, len(keptRows))
+	for len(queue) != 0 {
+		key := queue[0]
+		queue = queue[1:]
+		for _, i := range children[key] {
+			if isDropped[i] {
+				continue
+			}
+			isDropped[i] = true
+			dropped.add(tableName, columns, keptRows[i])
+			for _, fk := range selfFks {
+				index := columnIndex(columns, fk.ForeignColumnName)
+				if index == -1 || keptRows[i][index] == nil {
+					continue
+				}
+				queue = append(queue, fk.ForeignColumnName+"\x00"+repositories.AnyToPsqlString(keptRows[i][index]))
+			}
+		}
+	}
+	return isDropped
+}
+
+func columnIndex(columns []db.Column, columnName string) int {
+	return slices.IndexFunc(columns, func(column db.Column) bool { return column.ColumnName == columnName })
+}
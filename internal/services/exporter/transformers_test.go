@@ -0,0 +1,111 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/t1m4/db_part_dump/internal/db"
+	"github.com/t1m4/db_part_dump/transform"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTransformersTransform(t *testing.T) {
+	fksByTable := map[string][]db.Fk{
+		"orders":      {{ColumnName: "user_id", ForeignTableName: "users", ForeignColumnName: "id"}},
+		"order_items": {{ColumnName: "order_id", ForeignTableName: "orders", ForeignColumnName: "id"}},
+	}
+	dropped := newDroppedRows(fksByTable)
+	// Drop suspended users and upper case usernames of other users
+	transformers := []transform.RowTransformer{
+		transform.Func(func(tableName string, columns []transform.Column, values []any) (bool, error) {
+			if tableName != "users" {
+				return true, nil
+			}
+			if values[2] == "suspended" {
+				return false, nil
+			}
+			values[1] = "USER"
+			return true, nil
+		}),
+	}
+	userColumns := []db.Column{{ColumnName: "id"}, {ColumnName: "username"}, {ColumnName: "status"}}
+	usersTransform := transformersTransform("users", transformers, fksByTable["users"], dropped)
+	users := [][]any{{int64(1), "john_doe", "active"}, {int64(5), "mike_brown", "suspended"}}
+	expectedUsers := []bool{true, false}
+	for i, values := range users {
+		isKept, err := usersTransform(userColumns, values)
+		if isKept != expectedUsers[i] || err != nil {
+			t.Errorf("user %v: wrong result %v, %v", values[0], isKept, err)
+		}
+	}
+	if diff := cmp.Diff([]any{int64(1), "USER", "active"}, users[0]); diff != "" {
+		t.Errorf("values mismatch (-want +got):\n%s", diff)
+	}
+
+	orderColumns := []db.Column{{ColumnName: "id"}, {ColumnName: "user_id"}}
+	ordersTransform := transformersTransform("orders", transformers, fksByTable["orders"], dropped)
+	orders := [][]any{{int64(1), int64(1)}, {int64(8), int64(5)}}
+	expectedOrders := []bool{true, false}
+	for i, values := range orders {
+		isKept, err := ordersTransform(orderColumns, values)
+		if isKept != expectedOrders[i] || err != nil {
+			t.Errorf("order %v: wrong result %v, %v", values[0], isKept, err)
+		}
+	}
+
+	itemColumns := []db.Column{{ColumnName: "id"}, {ColumnName: "order_id"}}
+	itemsTransform := transformersTransform("order_items", transformers, fksByTable["order_items"], dropped)
+	items := [][]any{{int64(1), int64(1)}, {int64(2), int64(8)}, {int64(3), nil}}
+	expectedItems := []bool{true, false, true}
+	for i, values := range items {
+		isKept, err := itemsTransform(itemColumns, values)
+		if isKept != expectedItems[i] || err != nil {
+			t.Errorf("item %v: wrong result %v, %v", values[0], isKept, err)
+		}
+	}
+}
+
+func TestCascadeDrops(t *testing.T) {
+	selfFks := []db.Fk{{ColumnName: "parent_id", ForeignTableName: "comments", ForeignColumnName: "id"}}
+	dropped := newDroppedRows(map[string][]db.Fk{"comments": selfFks})
+	// Comment 1 is dropped, replies come before their parents
+	dropped.add("comments", []db.Column{{ColumnName: "id"}}, []any{int64(1)})
+	columns := []db.Column{{ColumnName: "id"}, {ColumnName: "parent_id"}}
+	keptRows := [][]any{{int64(4), int64(3)}, {int64(3), int64(2)}, {int64(2), int64(1)}, {int64(5), nil}}
+	isDropped := cascadeDrops("comments", selfFks, columns, keptRows, dropped)
+	expected := droppedRows{"comments": {"id": {"1": true, "2": true, "3": true, "4": true}}}
+	if diff := cmp.Diff(expected, dropped); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]bool{true, true, true, false}, isDropped); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReplayTransform(t *testing.T) {
+	columns := []db.Column{{ColumnName: "id"}, {ColumnName: "body"}}
+	rowTransform := replayTransform("id", map[string][]any{"1": {int64(1), "masked"}})
+	tests := []struct {
+		name           string
+		values         []any
+		expectedIsKept bool
+		expectedValues []any
+	}{
+		{name: "kept", values: []any{int64(1), "text"}, expectedIsKept: true, expectedValues: []any{int64(1), "masked"}},
+		{name: "dropped", values: []any{int64(2), "text"}, expectedIsKept: false, expectedValues: []any{int64(2), "text"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isKept, err := rowTransform(columns, test.values)
+			if err != nil {
+				t.Fatalf("wrong err: %v, expected %v", err, nil)
+			}
+			if isKept != test.expectedIsKept {
+				t.Errorf("wrong isKept: %v, expected %v", isKept, test.expectedIsKept)
+			}
+			if diff := cmp.Diff(test.expectedValues, test.values); diff != "" {
+				t.Errorf("%s mismatch (-want +got):\n%s", test.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package masking
+
+import (
+	"fmt"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+)
+
+// Column types of schema which can store masked text
+var schemaTextTypes = map[string]bool{
+	"text":              true,
+	"character varying": true,
+	"character":         true,
+	"citext":            true,
+}
+
+// Column types of schema which can store number of range method
+var schemaNumberTypes = map[string]bool{
+	"smallint":         true,
+	"integer":          true,
+	"bigint":           true,
+	"numeric":          true,
+	"real":             true,
+	"double precision": true,
+}
+
+// Column types of schema which can be pseudonymized
+var schemaPseudonymTypes = map[string]bool{
+	"smallint":          true,
+	"integer":           true,
+	"bigint":            true,
+	"uuid":              true,
+	"text":              true,
+	"character varying": true,
+	"character":         true,
+}
+
+// Check that masked columns exist and masked values fit them.
+// Columns are schema columns returned by GetColumns
+func CheckMasks(masks []config.Mask, columns []db.Column) error {
+	tables := make(map[string]bool)
+	columnsByName := make(map[string]db.Column, len(columns))
+	for _, column := range columns {
+		tables[column.TableName] = true
+		columnsByName[column.TableName+"."+column.ColumnName] = column
+	}
+	for _, mask := range masks {
+		if !tables[mask.Table] {
+			return fmt.Errorf("mask table %s does not exist", mask.Table)
+		}
+		column, ok := columnsByName[mask.Table+"."+mask.Column]
+		if !ok {
+			return fmt.Errorf("mask column %s.%s does not exist", mask.Table, mask.Column)
+		}
+		err := checkMaskColumn(mask, column)
+		if err != nil {
+			return fmt.Errorf("mask %s of column %s.%s: %w", mask.Method, mask.Table, mask.Column, err)
+		}
+	}
+	return nil
+}
+
+func checkMaskColumn(mask config.Mask, column db.Column) error {
+	switch mask.Method {
+	case constants.MASK_NULL:
+		if !column.IsNullable {
+			return fmt.Errorf("column is not nullable")
+		}
+	case constants.MASK_HASH:
+		if !schemaTextTypes[column.DataType] {
+			return fmt.Errorf("column type %s is not text", column.DataType)
+		}
+		// Hash does not fit short column
+		if column.MaxLength != 0 && column.MaxLength < hashLength {
+			return fmt.Errorf("column length %d is less than hash length %d", column.MaxLength, hashLength)
+		}
+	case constants.MASK_EMAIL:
+		if !schemaTextTypes[column.DataType] {
+			return fmt.Errorf("column type %s is not text", column.DataType)
+		}
+	case constants.MASK_PARTIAL:
+		if !schemaTextTypes[column.DataType] {
+			return fmt.Errorf("column type %s is not text", column.DataType)
+		}
+		// Values with equal last characters and length are masked equally
+		if column.IsUnique || column.IsPrimaryKey {
+			return fmt.Errorf("column is unique")
+		}
+	case constants.MASK_NAME:
+		if !schemaTextTypes[column.DataType] {
+			return fmt.Errorf("column type %s is not text", column.DataType)
+		}
+		// Few fake names break unique constraint
+		if column.IsUnique || column.IsPrimaryKey {
+			return fmt.Errorf("column is unique")
+		}
+	case constants.MASK_CONSTANT:
+		if column.IsUnique || column.IsPrimaryKey {
+			return fmt.Errorf("column is unique")
+		}
+	case constants.MASK_RANGE:
+		if !schemaNumberTypes[column.DataType] {
+			return fmt.Errorf("column type %s is not number", column.DataType)
+		}
+	case constants.MASK_PSEUDONYMIZE:
+		if !schemaPseudonymTypes[column.DataType] {
+			return fmt.Errorf("column type %s can not be pseudonymized", column.DataType)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package masking
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+	"github.com/t1m4/db_part_dump/internal/repositories"
+)
+
+// Count of last not masked characters for partial method by default
+const defaultKeep = 4
+
+// Length of hex hash for hash method
+const hashLength = 16
+
+// Methods which output is derived from value with secret
+var keyedMethods = map[string]bool{
+	constants.MASK_HASH:         true,
+	constants.MASK_EMAIL:        true,
+	constants.MASK_NAME:         true,
+	constants.MASK_PSEUDONYMIZE: true,
+	constants.MASK_RANGE:        true,
+}
+
+var firstNames = []string{"Alice", "Bob", "Carol", "David", "Emma", "Frank", "Grace", "Henry", "Irene", "Jack"}
+var lastNames = []string{"Smith", "Johnson", "Brown", "Taylor", "Miller", "Wilson", "Moore", "Clark", "Lewis", "Young"}
+
+// Masks by table and column name
+type Masker struct {
+	masksByTable map[string]map[string]config.Mask
+	secret       []byte // HMAC key of hash, email, name, range and pseudonymize masks
+	// Integer types of pseudonymized columns by table and column, shared by columns linked by fks
+	integerTypes map[string]map[string]string
+}
+
+func New(masks []config.Mask, secret []byte) *Masker {
+	masksByTable := make(map[string]map[string]config.Mask)
+	for _, mask := range masks {
+		if _, ok := masksByTable[mask.Table]; !ok {
+			masksByTable[mask.Table] = make(map[string]config.Mask)
+		}
+		masksByTable[mask.Table][mask.Column] = mask
+	}
+	return &Masker{masksByTable: masksByTable, secret: secret}
+}
+
+func (m *Masker) SetIntegerTypes(integerTypes map[string]map[string]string) {
+	m.integerTypes = integerTypes
+}
+
+func (m *Masker) Secret() []byte {
+	return m.secret
+}
+
+// Check if any mask pseudonymizes column
+func (m *Masker) HasPseudonyms() bool {
+	for _, masks := range m.masksByTable {
+		for _, mask := range masks {
+			if mask.Method == constants.MASK_PSEUDONYMIZE {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Check if any mask derives output from value with secret
+func (m *Masker) HasKeyedMasks() bool {
+	for _, masks := range m.masksByTable {
+		for _, mask := range masks {
+			if keyedMethods[mask.Method] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Create transform which masks column values of table rows. Return nil when table has no masks
+func (m *Masker) Transform(tableName string) repositories.RowTransform {
+	masks, ok := m.masksByTable[tableName]
+	if !ok {
+		return nil
+	}
+	return func(columns []db.Column, values []any) (bool, error) {
+		for i, column := range columns {
+			mask, ok := masks[column.ColumnName]
+			if !ok {
+				continue
+			}
+			if mask.Method != constants.MASK_PSEUDONYMIZE {
+				values[i] = MaskValue(m.secret, mask, column, values[i])
+				continue
+			}
+			if dataType, ok := m.integerTypes[tableName][column.ColumnName]; ok {
+				column.DataType = dataType
+			}
+			value, err := Pseudonymize(m.secret, column, values[i])
+			if err != nil {
+				return false, err
+			}
+			values[i] = value
+		}
+		return true, nil
+	}
+}
+
+// Mask one column value. NULL stays NULL.
+// hash, email, name and range are keyed by secret, so masked values can not be matched against hashes of guessed values
+func MaskValue(secret []byte, mask config.Mask, column db.Column, value any) any {
+	if value == nil || mask.Method == constants.MASK_NULL {
+		return nil
+	}
+	text := toString(value)
+	switch mask.Method {
+	case constants.MASK_CONSTANT:
+		return mask.Value
+	case constants.MASK_HASH:
+		return hashHex(secret, text)[:hashLength]
+	case constants.MASK_EMAIL:
+		return fmt.Sprintf("user_%s@example.com", hashHex(secret, text)[:hashLength])
+	case constants.MASK_NAME:
+		hash := hashNumber(secret, text)
+		return fmt.Sprintf("%s %s", firstNames[hash%uint64(len(firstNames))], lastNames[(hash>>32)%uint64(len(lastNames))])
+	case constants.MASK_PARTIAL:
+		return maskPartial(text, mask.Keep)
+	case constants.MASK_RANGE:
+		// Top 53 bits of hash give uniform float in [0, 1)
+		number := mask.Min + float64(hashNumber(secret, text)>>11)/(1<<53)*(mask.Max-mask.Min)
+		if strings.HasPrefix(column.DataType, "int") {
+			return int64(number)
+		}
+		return number
+	}
+	return value
+}
+
+// Replace all characters except last keep characters with *
+func maskPartial(text string, keep int) string {
+	if keep == 0 {
+		keep = defaultKeep
+	}
+	runes := []rune(text)
+	for i := 0; i < len(runes)-keep; i++ {
+		runes[i] = '*'
+	}
+	return string(runes)
+}
+
+func toString(value any) string {
+	if bytes, ok := value.([]byte); ok {
+		return string(bytes)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func hashHex(secret []byte, text string) string {
+	return hex.EncodeToString(hmacSum(secret, []byte(text)))
+}
+
+func hashNumber(secret []byte, text string) uint64 {
+	return binary.BigEndian.Uint64(hmacSum(secret, []byte(text))[:8])
+}
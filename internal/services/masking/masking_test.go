@@ -0,0 +1,128 @@
+package masking
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMaskValue(t *testing.T) {
+	type TestData struct {
+		name     string
+		mask     config.Mask
+		value    any
+		expected any
+	}
+	column := db.Column{TableName: "users", ColumnName: "email", DataType: "varchar"}
+	tests := []TestData{
+		{name: "test null", mask: config.Mask{Method: constants.MASK_NULL}, value: "john@example.com", expected: nil},
+		{name: "test null value", mask: config.Mask{Method: constants.MASK_CONSTANT, Value: "x"}, value: nil, expected: nil},
+		{name: "test constant", mask: config.Mask{Method: constants.MASK_CONSTANT, Value: "x"}, value: "john", expected: "x"},
+		{name: "test partial", mask: config.Mask{Method: constants.MASK_PARTIAL}, value: "4111111111111111", expected: "************1111"},
+		{name: "test partial keep", mask: config.Mask{Method: constants.MASK_PARTIAL, Keep: 2}, value: []byte("abc"), expected: "*bc"},
+		{name: "test partial short", mask: config.Mask{Method: constants.MASK_PARTIAL}, value: "abc", expected: "abc"},
+	}
+	for _, test := range tests {
+		actual := MaskValue(secret, test.mask, column, test.value)
+		if diff := cmp.Diff(test.expected, actual); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", test.name, diff)
+		}
+	}
+}
+
+func TestMaskValueDeterministic(t *testing.T) {
+	column := db.Column{TableName: "users", ColumnName: "email", DataType: "varchar"}
+	methods := []string{constants.MASK_HASH, constants.MASK_EMAIL, constants.MASK_NAME}
+	for _, method := range methods {
+		mask := config.Mask{Method: method}
+		first := MaskValue(secret, mask, column, "john@example.com")
+		second := MaskValue(secret, mask, column, "john@example.com")
+		other := MaskValue(secret, mask, column, "jane@example.com")
+		if first != second {
+			t.Errorf("%s: equal values are masked differently: %v, %v", method, first, second)
+		}
+		if method != constants.MASK_NAME && first == other {
+			t.Errorf("%s: different values are masked equally: %v", method, first)
+		}
+		if method != constants.MASK_NAME && first == MaskValue([]byte("other"), mask, column, "john@example.com") {
+			t.Errorf("%s: masked value does not depend on secret: %v", method, first)
+		}
+	}
+	email := MaskValue(secret, config.Mask{Method: constants.MASK_EMAIL}, column, "john@example.com").(string)
+	if !strings.HasSuffix(email, "@example.com") {
+		t.Errorf("wrong fake email %s", email)
+	}
+}
+
+func TestMaskValueRange(t *testing.T) {
+	mask := config.Mask{Method: constants.MASK_RANGE, Min: 10, Max: 20}
+	intValue, ok := MaskValue(secret, mask, db.Column{DataType: "int4"}, int64(1)).(int64)
+	if !ok || intValue < 10 || intValue > 20 {
+		t.Errorf("wrong int range value %v", intValue)
+	}
+	floatValue, ok := MaskValue(secret, mask, db.Column{DataType: "numeric"}, 1.5).(float64)
+	if !ok || floatValue < 10 || floatValue > 20 {
+		t.Errorf("wrong float range value %v", floatValue)
+	}
+	if floatValue != MaskValue(secret, mask, db.Column{DataType: "numeric"}, 1.5) {
+		t.Errorf("equal values are masked differently")
+	}
+	if floatValue == MaskValue([]byte("other"), mask, db.Column{DataType: "numeric"}, 1.5) {
+		t.Errorf("masked value does not depend on secret: %v", floatValue)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	masker := New([]config.Mask{{Table: "users", Column: "email", Method: constants.MASK_NULL}}, nil)
+	if masker.Transform("orders") != nil {
+		t.Errorf("expected nil transform for table without masks")
+	}
+	columns := []db.Column{{ColumnName: "id"}, {ColumnName: "email"}}
+	values := []any{int64(1), "john@example.com"}
+	isKept, err := masker.Transform("users")(columns, values)
+	if !isKept || err != nil {
+		t.Errorf("wrong result %v, %v", isKept, err)
+	}
+	if diff := cmp.Diff([]any{int64(1), nil}, values); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckMasks(t *testing.T) {
+	type TestData struct {
+		name  string
+		mask  config.Mask
+		isErr bool
+	}
+	columns := []db.Column{
+		{TableName: "users", ColumnName: "id", DataType: "integer", IsPrimaryKey: true, IsUnique: true},
+		{TableName: "users", ColumnName: "username", DataType: "character varying", IsUnique: true},
+		{TableName: "users", ColumnName: "code", DataType: "character varying", IsUnique: true, MaxLength: 10},
+		{TableName: "users", ColumnName: "status", DataType: "character varying", IsNullable: true},
+		{TableName: "users", ColumnName: "created_at", DataType: "timestamp without time zone"},
+	}
+	tests := []TestData{
+		{name: "test hash text", mask: config.Mask{Table: "users", Column: "username", Method: constants.MASK_HASH}},
+		{name: "test null nullable", mask: config.Mask{Table: "users", Column: "status", Method: constants.MASK_NULL}},
+		{name: "test pseudonymize int", mask: config.Mask{Table: "users", Column: "id", Method: constants.MASK_PSEUDONYMIZE}},
+		{name: "test unknown table", mask: config.Mask{Table: "orders", Column: "id", Method: constants.MASK_HASH}, isErr: true},
+		{name: "test unknown column", mask: config.Mask{Table: "users", Column: "email", Method: constants.MASK_HASH}, isErr: true},
+		{name: "test hash int", mask: config.Mask{Table: "users", Column: "id", Method: constants.MASK_HASH}, isErr: true},
+		{name: "test hash short", mask: config.Mask{Table: "users", Column: "code", Method: constants.MASK_HASH}, isErr: true},
+		{name: "test partial unique", mask: config.Mask{Table: "users", Column: "username", Method: constants.MASK_PARTIAL}, isErr: true},
+		{name: "test name unique", mask: config.Mask{Table: "users", Column: "username", Method: constants.MASK_NAME}, isErr: true},
+		{name: "test null not nullable", mask: config.Mask{Table: "users", Column: "created_at", Method: constants.MASK_NULL}, isErr: true},
+		{name: "test range timestamp", mask: config.Mask{Table: "users", Column: "created_at", Method: constants.MASK_RANGE}, isErr: true},
+	}
+	for _, test := range tests {
+		err := CheckMasks([]config.Mask{test.mask}, columns)
+		if (err != nil) != test.isErr {
+			t.Errorf("%s: wrong err %v", test.name, err)
+		}
+	}
+}
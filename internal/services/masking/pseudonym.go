@@ -0,0 +1,223 @@
+package masking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+)
+
+// Count of rounds of Feistel network for integer keys
+const feistelRounds = 4
+
+// Bits of integer type domain. Values keep type range, so pseudonyms fit the column
+var integerBits = map[string]int{
+	"int2": 16,
+	"int4": 32,
+	"int8": 64,
+}
+
+// Integer types of schema columns by GetColumns and their row column types
+var schemaIntegerTypes = map[string]string{
+	"smallint": "int2",
+	"integer":  "int4",
+	"bigint":   "int8",
+}
+
+var textTypes = map[string]bool{
+	"text":    true,
+	"varchar": true,
+	"bpchar":  true,
+}
+
+// Map value to pseudonym with keyed HMAC. Equal inputs give equal outputs across tables and runs.
+// Integers are permuted inside type range, so different keys never get equal pseudonyms
+func Pseudonymize(secret []byte, column db.Column, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if bits, ok := integerBits[column.DataType]; ok {
+		number, ok := value.(int64)
+		if !ok {
+			return nil, fmt.Errorf("column %s.%s value %v is not integer", column.TableName, column.ColumnName, value)
+		}
+		return pseudonymizeInteger(secret, number, bits), nil
+	}
+	text := toString(value)
+	mac := hmacSum(secret, []byte(text))
+	if column.DataType == "uuid" {
+		return formatUuid(mac[:16]), nil
+	}
+	if textTypes[column.DataType] {
+		return hex.EncodeToString(mac)[:hashLength], nil
+	}
+	return nil, fmt.Errorf("column %s.%s type %s can not be pseudonymized", column.TableName, column.ColumnName, column.DataType)
+}
+
+// Permute absolute value inside [0, 2^(bits-1)) and keep sign
+func pseudonymizeInteger(secret []byte, number int64, bits int) int64 {
+	limit := uint64(1) << (bits - 1)
+	magnitude := uint64(number)
+	if number < 0 {
+		magnitude = uint64(-number)
+	}
+	if number == math.MinInt64 || magnitude >= limit {
+		return number
+	}
+	// Cycle walking keeps permutation of [0, 2^bits) inside [0, limit)
+	result := feistel(secret, magnitude, bits)
+	for result >= limit {
+		result = feistel(secret, result, bits)
+	}
+	if number < 0 {
+		return -int64(result)
+	}
+	return int64(result)
+}
+
+// Keyed permutation of [0, 2^bits)
+func feistel(secret []byte, value uint64, bits int) uint64 {
+	half := bits / 2
+	mask := uint64(1)<<half - 1
+	left, right := value>>half, value&mask
+	for round := 0; round < feistelRounds; round++ {
+		data := make([]byte, 9)
+		data[0] = byte(round)
+		binary.BigEndian.PutUint64(data[1:], right)
+		f := binary.BigEndian.Uint64(hmacSum(secret, data)[:8]) & mask
+		left, right = right, left^f
+	}
+	return left<<half | right
+}
+
+func hmacSum(secret []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// Format bytes as version 4 uuid
+func formatUuid(b []byte) string {
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+type columnNode struct{ table, column string }
+
+// Get columns linked by fks in both directions. fksByTable has outgoing fks of tables
+func linkedColumns(fksByTable map[string][]db.Fk) map[columnNode][]columnNode {
+	edges := make(map[columnNode][]columnNode)
+	tableNames := make([]string, 0, len(fksByTable))
+	for tableName := range fksByTable {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+	for _, tableName := range tableNames {
+		for _, fk := range fksByTable[tableName] {
+			from := columnNode{tableName, fk.ColumnName}
+			to := columnNode{fk.ForeignTableName, fk.ForeignColumnName}
+			edges[from] = append(edges[from], to)
+			edges[to] = append(edges[to], from)
+		}
+	}
+	return edges
+}
+
+// Add pseudonymize masks to all columns linked with pseudonymized columns by fks,
+// so joins between pseudonymized keys keep working. fksByTable has outgoing fks of tables.
+// Linked column with other mask is error, because its values would not match pseudonyms
+func PropagatePseudonyms(masks []config.Mask, fksByTable map[string][]db.Fk) ([]config.Mask, error) {
+	edges := linkedColumns(fksByTable)
+	methods := make(map[columnNode]string, len(masks))
+	queue := make([]columnNode, 0)
+	for _, mask := range masks {
+		current := columnNode{mask.Table, mask.Column}
+		methods[current] = mask.Method
+		if mask.Method == constants.MASK_PSEUDONYMIZE {
+			queue = append(queue, current)
+		}
+	}
+	result := append(make([]config.Mask, 0, len(masks)), masks...)
+	for len(queue) != 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range edges[current] {
+			method, ok := methods[next]
+			if ok && method != constants.MASK_PSEUDONYMIZE {
+				return nil, fmt.Errorf(
+					"column %s.%s is linked with pseudonymized column %s.%s and can not have mask %s",
+					next.table, next.column, current.table, current.column, method,
+				)
+			}
+			if ok {
+				continue
+			}
+			methods[next] = constants.MASK_PSEUDONYMIZE
+			queue = append(queue, next)
+			result = append(result, config.Mask{Table: next.table, Column: next.column, Method: constants.MASK_PSEUDONYMIZE})
+		}
+	}
+	return result, nil
+}
+
+// Get integer type of pseudonymized integer columns by table and column.
+// Columns linked by fks share smallest integer type among them, so equal keys get equal pseudonyms
+// which fit every linked column. Columns are schema columns returned by GetColumns
+func IntegerTypes(masks []config.Mask, fksByTable map[string][]db.Fk, columns []db.Column) map[string]map[string]string {
+	types := make(map[columnNode]string, len(columns))
+	for _, column := range columns {
+		if dataType, ok := schemaIntegerTypes[column.DataType]; ok {
+			types[columnNode{column.TableName, column.ColumnName}] = dataType
+		}
+	}
+	isPseudonymized := make(map[columnNode]bool, len(masks))
+	for _, mask := range masks {
+		if mask.Method == constants.MASK_PSEUDONYMIZE {
+			isPseudonymized[columnNode{mask.Table, mask.Column}] = true
+		}
+	}
+	edges := linkedColumns(fksByTable)
+	result := make(map[string]map[string]string)
+	visited := make(map[columnNode]bool, len(masks))
+	for _, mask := range masks {
+		start := columnNode{mask.Table, mask.Column}
+		if !isPseudonymized[start] || visited[start] {
+			continue
+		}
+		visited[start] = true
+		component := []columnNode{start}
+		for i := 0; i < len(component); i++ {
+			for _, next := range edges[component[i]] {
+				if isPseudonymized[next] && !visited[next] {
+					visited[next] = true
+					component = append(component, next)
+				}
+			}
+		}
+		smallest := ""
+		for _, current := range component {
+			dataType, ok := types[current]
+			if ok && (smallest == "" || integerBits[dataType] < integerBits[smallest]) {
+				smallest = dataType
+			}
+		}
+		for _, current := range component {
+			if _, ok := types[current]; !ok {
+				continue
+			}
+			if _, ok := result[current.table]; !ok {
+				result[current.table] = make(map[string]string)
+			}
+			result[current.table][current.column] = smallest
+		}
+	}
+	return result
+}
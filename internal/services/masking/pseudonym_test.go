@@ -0,0 +1,157 @@
+package masking
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var secret = []byte("secret")
+
+func TestPseudonymize(t *testing.T) {
+	tests := []struct {
+		name     string
+		dataType string
+		value    any
+	}{
+		{name: "int2", dataType: "int2", value: int64(7)},
+		{name: "int4", dataType: "int4", value: int64(42)},
+		{name: "int8", dataType: "int8", value: int64(1 << 40)},
+		{name: "negative", dataType: "int4", value: int64(-42)},
+		{name: "uuid", dataType: "uuid", value: []byte("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11")},
+		{name: "text", dataType: "varchar", value: "john@example.com"},
+	}
+	for _, test := range tests {
+		column := db.Column{DataType: test.dataType}
+		first, err := Pseudonymize(secret, column, test.value)
+		if err != nil {
+			t.Fatalf("%s wrong err: %v", test.name, err)
+		}
+		second, _ := Pseudonymize(secret, column, test.value)
+		if first != second {
+			t.Errorf("%s pseudonym is not deterministic: %v, %v", test.name, first, second)
+		}
+		if first == test.value {
+			t.Errorf("%s value is not pseudonymized: %v", test.name, first)
+		}
+		other, _ := Pseudonymize([]byte("other"), column, test.value)
+		if first == other {
+			t.Errorf("%s pseudonym does not depend on secret: %v", test.name, first)
+		}
+	}
+	null, err := Pseudonymize(secret, db.Column{DataType: "int4"}, nil)
+	if null != nil || err != nil {
+		t.Errorf("wrong result %v, %v for NULL", null, err)
+	}
+	_, err = Pseudonymize(secret, db.Column{DataType: "numeric"}, 1.5)
+	if err == nil {
+		t.Errorf("expected err for not supported type")
+	}
+}
+
+func TestPseudonymizeFormat(t *testing.T) {
+	value, _ := Pseudonymize(secret, db.Column{DataType: "uuid"}, "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11")
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !uuid.MatchString(value.(string)) {
+		t.Errorf("wrong uuid %v", value)
+	}
+	value, _ = Pseudonymize(secret, db.Column{DataType: "int4"}, int64(-5))
+	if number := value.(int64); number >= 0 {
+		t.Errorf("sign is not kept %v", number)
+	}
+}
+
+func TestPseudonymizeIntegerIsPermutation(t *testing.T) {
+	seen := make(map[int64]int64)
+	for number := int64(0); number < 1<<15; number++ {
+		result := pseudonymizeInteger(secret, number, 16)
+		if result < 0 || result >= 1<<15 {
+			t.Fatalf("%d is out of range: %d", number, result)
+		}
+		if previous, ok := seen[result]; ok {
+			t.Fatalf("%d and %d have equal pseudonym %d", previous, number, result)
+		}
+		seen[result] = number
+	}
+}
+
+func TestPropagatePseudonyms(t *testing.T) {
+	masks := []config.Mask{
+		{Table: "users", Column: "id", Method: constants.MASK_PSEUDONYMIZE},
+		{Table: "users", Column: "email", Method: constants.MASK_NULL},
+	}
+	fksByTable := map[string][]db.Fk{
+		"orders": {{ColumnName: "user_id", ForeignTableName: "users", ForeignColumnName: "id"}},
+		"order_items": {
+			{ColumnName: "order_id", ForeignTableName: "orders", ForeignColumnName: "id"},
+			{ColumnName: "user_id", ForeignTableName: "orders", ForeignColumnName: "user_id"},
+		},
+	}
+	expected := []config.Mask{
+		masks[0],
+		masks[1],
+		{Table: "orders", Column: "user_id", Method: constants.MASK_PSEUDONYMIZE},
+		{Table: "order_items", Column: "user_id", Method: constants.MASK_PSEUDONYMIZE},
+	}
+	actual, err := PropagatePseudonyms(masks, fksByTable)
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("PropagatePseudonyms mismatch (-want +got):\n%s", diff)
+	}
+
+	// Linked column with other mask would not match pseudonyms
+	masks = append(masks, config.Mask{Table: "user_payment_methods", Column: "user_id", Method: constants.MASK_NULL})
+	fksByTable["user_payment_methods"] = []db.Fk{{ColumnName: "user_id", ForeignTableName: "users", ForeignColumnName: "id"}}
+	_, err = PropagatePseudonyms(masks, fksByTable)
+	if err == nil {
+		t.Errorf("expected err for linked column with other mask")
+	}
+}
+
+func TestIntegerTypes(t *testing.T) {
+	masks := []config.Mask{
+		{Table: "users", Column: "id", Method: constants.MASK_PSEUDONYMIZE},
+		{Table: "orders", Column: "user_id", Method: constants.MASK_PSEUDONYMIZE},
+		{Table: "orders", Column: "id", Method: constants.MASK_PSEUDONYMIZE},
+	}
+	fksByTable := map[string][]db.Fk{
+		"orders": {{ColumnName: "user_id", ForeignTableName: "users", ForeignColumnName: "id"}},
+	}
+	columns := []db.Column{
+		{TableName: "users", ColumnName: "id", DataType: "integer"},
+		{TableName: "orders", ColumnName: "id", DataType: "bigint"},
+		{TableName: "orders", ColumnName: "user_id", DataType: "bigint"},
+	}
+	expected := map[string]map[string]string{
+		"users":  {"id": "int4"},
+		"orders": {"id": "int8", "user_id": "int4"},
+	}
+	actual := IntegerTypes(masks, fksByTable, columns)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("IntegerTypes mismatch (-want +got):\n%s", diff)
+	}
+
+	// Bigint fk gets the same pseudonym as integer pk it references
+	masker := New(masks, secret)
+	masker.SetIntegerTypes(actual)
+	values := []any{int64(42)}
+	_, err := masker.Transform("users")([]db.Column{{ColumnName: "id", DataType: "int4"}}, values)
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	fkValues := []any{int64(42)}
+	_, err = masker.Transform("orders")([]db.Column{{ColumnName: "user_id", DataType: "int8"}}, fkValues)
+	if err != nil {
+		t.Fatalf("wrong err: %v, expected %v", err, nil)
+	}
+	if diff := cmp.Diff(values, fkValues); diff != "" {
+		t.Errorf("pseudonyms mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,280 @@
+package pii
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/repositories"
+	"github.com/t1m4/db_part_dump/internal/schemas"
+)
+
+// Kinds of personal data
+const (
+	EMAIL = "email"
+	PHONE = "phone"
+	CARD  = "card"
+	IBAN  = "iban"
+	IP    = "ip"
+	NAME  = "name"
+	BIRTH = "birth_date"
+	ID    = "national_id"
+)
+
+// Kinds detected by value in order of DetectValue. Kind which goes first wins equal counts
+var valueKinds = []string{EMAIL, CARD, IBAN, IP, PHONE}
+
+// Min percentage of sampled not empty values of column matching kind
+const minMatchedPercent = 50
+
+// Parts of column names by kind. Checked in order, so more specific kinds go first
+var namePatterns = []struct {
+	kind  string
+	parts []string
+}{
+	{kind: EMAIL, parts: []string{"email", "e_mail"}},
+	{kind: PHONE, parts: []string{"phone", "mobile", "msisdn"}},
+	{kind: CARD, parts: []string{"card_number", "card_no", "cc_number", "pan"}},
+	{kind: IBAN, parts: []string{"iban"}},
+	{kind: IP, parts: []string{"ip_address", "ip_addr", "last_ip", "remote_ip"}},
+	{kind: NAME, parts: []string{"first_name", "last_name", "full_name", "surname"}},
+	{kind: BIRTH, parts: []string{"birth", "dob"}},
+	{kind: ID, parts: []string{"ssn", "passport", "tax_id"}},
+}
+
+var emailRegex = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[a-zA-Z]{2,}$`)
+var phoneRegex = regexp.MustCompile(`^\+?[0-9(][0-9 ()\-.]{6,18}[0-9]$`)
+var dateRegex = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}`)
+var ibanRegex = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+
+// Column with likely personal data
+type Finding struct {
+	Table  string
+	Column string
+	Kind   string
+	Source string // name or values
+}
+
+// Get kind of personal data by column name
+func DetectName(columnName string) (string, bool) {
+	columnName = strings.ToLower(columnName)
+	words := strings.Split(columnName, "_")
+	for _, pattern := range namePatterns {
+		for _, part := range pattern.parts {
+			// Short parts must be whole words, e.g. pan is not part of company
+			if strings.Contains(part, "_") || len(part) > 4 {
+				if strings.Contains(columnName, part) {
+					return pattern.kind, true
+				}
+				continue
+			}
+			for _, word := range words {
+				if word == part {
+					return pattern.kind, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// Get kind of personal data by value
+func DetectValue(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		return "", false
+	case emailRegex.MatchString(value):
+		return EMAIL, true
+	case isCard(value):
+		return CARD, true
+	case isIban(value):
+		return IBAN, true
+	case net.ParseIP(value) != nil:
+		return IP, true
+	case isPhone(value):
+		return PHONE, true
+	}
+	return "", false
+}
+
+// Phone has 7-15 digits and starts with + or has separators, so plain numeric codes are skipped
+func isPhone(value string) bool {
+	if !phoneRegex.MatchString(value) || dateRegex.MatchString(value) {
+		return false
+	}
+	digits := countDigits(value)
+	if digits < 7 || digits > 15 {
+		return false
+	}
+	return strings.HasPrefix(value, "+") || digits != len(value)
+}
+
+// Card number has 13-19 digits, optionally separated by spaces or dashes, and valid Luhn checksum
+func isCard(value string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(value)
+	if len(digits) < 13 || len(digits) > 19 || countDigits(digits) != len(digits) {
+		return false
+	}
+	return IsLuhn(digits)
+}
+
+// Check Luhn checksum of digits
+func IsLuhn(digits string) bool {
+	sum := 0
+	isDouble := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if isDouble {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		isDouble = !isDouble
+	}
+	return sum%10 == 0
+}
+
+// Check IBAN format and mod 97 checksum
+func isIban(value string) bool {
+	value = strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+	if !ibanRegex.MatchString(value) {
+		return false
+	}
+	rearranged := value[4:] + value[:4]
+	var numeric strings.Builder
+	for _, char := range rearranged {
+		if unicode.IsLetter(char) {
+			numeric.WriteString(fmt.Sprintf("%d", char-'A'+10))
+		} else {
+			numeric.WriteRune(char)
+		}
+	}
+	number, ok := new(big.Int).SetString(numeric.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(number, big.NewInt(97)).Int64() == 1
+}
+
+func countDigits(value string) int {
+	count := 0
+	for _, char := range value {
+		if unicode.IsDigit(char) {
+			count++
+		}
+	}
+	return count
+}
+
+// Get kind of values of sampled column when most of not empty values match the same kind.
+// Kind with the most matched values is chosen
+func detectValues(values []any) (string, bool) {
+	counts := make(map[string]int)
+	total := 0
+	for _, value := range values {
+		text, ok := toText(value)
+		if !ok || strings.TrimSpace(text) == "" {
+			continue
+		}
+		total++
+		if kind, ok := DetectValue(text); ok {
+			counts[kind]++
+		}
+	}
+	bestKind := ""
+	for _, kind := range valueKinds {
+		if counts[kind] > counts[bestKind] {
+			bestKind = kind
+		}
+	}
+	if bestKind == "" || counts[bestKind]*100 < total*minMatchedPercent {
+		return "", false
+	}
+	return bestKind, true
+}
+
+// Only text values are checked, numbers and dates are not personal data by value
+func toText(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	}
+	return "", false
+}
+
+// Scan columns of dumped tables by name and sampled values
+func Scan(
+	ctx context.Context,
+	repo repositories.RepositoriesI,
+	schemaName string,
+	tablePks []*schemas.Table,
+	sampleSize int,
+) ([]Finding, error) {
+	columns, err := repo.GetColumns(ctx, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	columnNamesByTable := make(map[string][]string)
+	for _, column := range columns {
+		columnNamesByTable[column.TableName] = append(columnNamesByTable[column.TableName], column.ColumnName)
+	}
+	findings := make([]Finding, 0)
+	for _, tablePk := range tablePks {
+		rows, err := repo.GetRowsSample(ctx, schemaName, tablePk, sampleSize)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, scanRows(tablePk.Name, columnNamesByTable[tablePk.Name], rows)...)
+	}
+	return findings, nil
+}
+
+// Find columns with personal data by names and sampled rows of table
+func scanRows(tableName string, columnNames []string, rows []map[string]any) []Finding {
+	valuesByColumn := make(map[string][]any)
+	for _, row := range rows {
+		for columnName, value := range row {
+			valuesByColumn[columnName] = append(valuesByColumn[columnName], value)
+		}
+	}
+	columnNames = slices.Clone(columnNames)
+	sort.Strings(columnNames)
+	findings := make([]Finding, 0)
+	for _, columnName := range columnNames {
+		if kind, ok := DetectName(columnName); ok {
+			findings = append(findings, Finding{Table: tableName, Column: columnName, Kind: kind, Source: "name"})
+			continue
+		}
+		if kind, ok := detectValues(valuesByColumn[columnName]); ok {
+			findings = append(findings, Finding{Table: tableName, Column: columnName, Kind: kind, Source: "values"})
+		}
+	}
+	return findings
+}
+
+// Get findings which columns have no masks
+func Uncovered(findings []Finding, masks []config.Mask) []Finding {
+	masked := make(map[[2]string]bool, len(masks))
+	for _, mask := range masks {
+		masked[[2]string{mask.Table, mask.Column}] = true
+	}
+	result := make([]Finding, 0)
+	for _, finding := range findings {
+		if !masked[[2]string{finding.Table, finding.Column}] {
+			result = append(result, finding)
+		}
+	}
+	return result
+}
@@ -0,0 +1,145 @@
+package pii
+
+import (
+	"testing"
+	"time"
+
+	"github.com/t1m4/db_part_dump/config"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDetectName(t *testing.T) {
+	type TestData struct {
+		columnName string
+		expected   string
+	}
+	tests := []TestData{
+		{columnName: "email", expected: EMAIL},
+		{columnName: "contact_email_address", expected: EMAIL},
+		{columnName: "mobile_phone", expected: PHONE},
+		{columnName: "card_number", expected: CARD},
+		{columnName: "pan", expected: CARD},
+		{columnName: "company", expected: ""},
+		{columnName: "last_ip", expected: IP},
+		{columnName: "first_name", expected: NAME},
+		{columnName: "date_of_birth", expected: BIRTH},
+		{columnName: "status", expected: ""},
+	}
+	for _, test := range tests {
+		actual, _ := DetectName(test.columnName)
+		if actual != test.expected {
+			t.Errorf("%s: got %s, expected %s", test.columnName, actual, test.expected)
+		}
+	}
+}
+
+func TestDetectValue(t *testing.T) {
+	type TestData struct {
+		value    string
+		expected string
+	}
+	tests := []TestData{
+		{value: "john@example.com", expected: EMAIL},
+		{value: "4111111111111111", expected: CARD},
+		{value: "4111 1111 1111 1111", expected: CARD},
+		{value: "4111111111111112", expected: ""},
+		{value: "DE89370400440532013000", expected: IBAN},
+		{value: "DE89370400440532013001", expected: ""},
+		{value: "192.168.0.1", expected: IP},
+		{value: "2001:db8::1", expected: IP},
+		{value: "+1 555-010-0100", expected: PHONE},
+		{value: "(555) 010-0100", expected: PHONE},
+		{value: "12345678", expected: ""},
+		{value: "2025-01-01 10:00:00", expected: ""},
+		{value: "john_doe", expected: ""},
+		{value: "", expected: ""},
+	}
+	for _, test := range tests {
+		actual, _ := DetectValue(test.value)
+		if actual != test.expected {
+			t.Errorf("%s: got %s, expected %s", test.value, actual, test.expected)
+		}
+	}
+}
+
+func TestIsLuhn(t *testing.T) {
+	if !IsLuhn("79927398713") {
+		t.Errorf("valid number is not detected")
+	}
+	if IsLuhn("79927398710") {
+		t.Errorf("invalid number is detected")
+	}
+}
+
+func TestDetectValues(t *testing.T) {
+	type TestData struct {
+		name         string
+		values       []any
+		expectedKind string
+		expectedOk   bool
+	}
+	tests := []TestData{
+		{
+			name:         "test most matched kind",
+			values:       []any{"10.0.0.1", "john@example.com", "10.0.0.2", "10.0.0.3"},
+			expectedKind: IP,
+			expectedOk:   true,
+		},
+		{
+			name:         "test equal counts",
+			values:       []any{"10.0.0.1", "john@example.com", "10.0.0.2", "jane@example.com"},
+			expectedKind: EMAIL,
+			expectedOk:   true,
+		},
+		{
+			name:   "test too few matched",
+			values: []any{"10.0.0.1", "john@example.com", "bob", "alice"},
+		},
+		{
+			name:   "test empty values",
+			values: []any{nil, " ", int64(1)},
+		},
+	}
+	for _, test := range tests {
+		// Map order must not change result
+		for range 10 {
+			kind, ok := detectValues(test.values)
+			if kind != test.expectedKind || ok != test.expectedOk {
+				t.Errorf("%s: wrong kind %s %v, expected %s %v", test.name, kind, ok, test.expectedKind, test.expectedOk)
+				break
+			}
+		}
+	}
+}
+
+func TestScanRows(t *testing.T) {
+	rows := []map[string]any{
+		{"id": int64(1), "login": "john@example.com", "contact": []byte("+1 555-010-0100"), "created_at": time.Now()},
+		{"id": int64(2), "login": "jane@example.com", "contact": nil, "created_at": time.Now()},
+		{"id": int64(3), "login": "bob", "contact": nil, "created_at": time.Now()},
+	}
+	columnNames := []string{"id", "login", "contact", "created_at", "first_name"}
+	expected := []Finding{
+		{Table: "users", Column: "contact", Kind: PHONE, Source: "values"},
+		{Table: "users", Column: "first_name", Kind: NAME, Source: "name"},
+		{Table: "users", Column: "login", Kind: EMAIL, Source: "values"},
+	}
+	actual := scanRows("users", columnNames, rows)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("scanRows mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUncovered(t *testing.T) {
+	findings := []Finding{
+		{Table: "users", Column: "email", Kind: EMAIL, Source: "name"},
+		{Table: "user_payment_methods", Column: "card_number", Kind: CARD, Source: "name"},
+	}
+	masks := []config.Mask{{Table: "users", Column: "email", Method: "email"}}
+	expected := findings[1:]
+	actual := Uncovered(findings, masks)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("Uncovered mismatch (-want +got):\n%s", diff)
+	}
+}
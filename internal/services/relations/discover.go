@@ -0,0 +1,124 @@
+package relations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/db"
+	"github.com/t1m4/db_part_dump/internal/repositories"
+)
+
+// Types which values can be used as keys
+var keyTypes = map[string]bool{
+	"smallint":          true,
+	"integer":           true,
+	"bigint":            true,
+	"text":              true,
+	"character varying": true,
+	"character":         true,
+	"uuid":              true,
+}
+
+type DiscoverOptions struct {
+	SampleSize  int     // Count of distinct values sampled from every column
+	MaxChecks   int     // Count of column pairs which can be checked
+	MinCoverage float64 // Minimal percentage of sampled values found in foreign column
+}
+
+type Candidate struct {
+	Relation config.Relation
+	Sampled  int
+	Coverage float64
+}
+
+// Get column pairs which may be fks: column of one table and pk or unique column of other table with same type.
+// Columns that are already fks or pks are skipped
+func DiscoverCandidates(columns []db.Column) []config.Relation {
+	pks := getSinglePks(columns)
+	targets := make([]db.Column, 0)
+	for _, column := range columns {
+		if !keyTypes[column.DataType] {
+			continue
+		}
+		if pk, ok := pks[column.TableName]; (ok && pk.ColumnName == column.ColumnName) || column.IsUnique {
+			targets = append(targets, column)
+		}
+	}
+	candidates := make([]config.Relation, 0)
+	for _, column := range columns {
+		if column.IsForeignKey || column.IsPrimaryKey || !keyTypes[column.DataType] {
+			continue
+		}
+		for _, target := range targets {
+			if target.TableName == column.TableName || !IsCompatibleTypes(column.DataType, target.DataType) {
+				continue
+			}
+			candidates = append(candidates, config.Relation{
+				Table:         column.TableName,
+				Column:        column.ColumnName,
+				ForeignTable:  target.TableName,
+				ForeignColumn: target.ColumnName,
+			})
+		}
+	}
+	// Check first pairs where column name mentions foreign table
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return isNameMatched(candidates[i]) && !isNameMatched(candidates[j])
+	})
+	return candidates
+}
+
+func isNameMatched(relation config.Relation) bool {
+	return strings.Contains(relation.ForeignTable, strings.TrimSuffix(strings.TrimSuffix(relation.Column, "_id"), "_by"))
+}
+
+// Check inclusion dependencies of candidates using sampled values within checks budget
+func Discover(
+	ctx context.Context,
+	repo repositories.RepositoriesI,
+	schemaName string,
+	options DiscoverOptions,
+) ([]Candidate, error) {
+	columns, err := repo.GetColumns(ctx, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	relations := DiscoverCandidates(columns)
+	if len(relations) > options.MaxChecks {
+		slog.Warn(fmt.Sprintf("Checks budget exceeded, %d of %d column pairs are checked", options.MaxChecks, len(relations)))
+		relations = relations[:options.MaxChecks]
+	}
+	result := make([]Candidate, 0)
+	for _, relation := range relations {
+		matched, total, err := repo.GetInclusionCoverage(ctx, schemaName, relation, options.SampleSize)
+		if err != nil {
+			return nil, err
+		}
+		if total == 0 {
+			continue
+		}
+		coverage := float64(matched) * 100 / float64(total)
+		if coverage < options.MinCoverage {
+			continue
+		}
+		result = append(result, Candidate{Relation: relation, Sampled: total, Coverage: coverage})
+	}
+	return result, nil
+}
+
+// Write candidates as config snippet nested under settings with coverage comments
+func WriteCandidates(w io.Writer, candidates []Candidate) error {
+	var snippet strings.Builder
+	snippet.WriteString(relationsHeader)
+	for _, candidate := range candidates {
+		snippet.WriteString(fmt.Sprintf("    # coverage %.1f%% of %d sampled values\n", candidate.Coverage, candidate.Sampled))
+		writeRelation(&snippet, candidate.Relation)
+	}
+	_, err := io.WriteString(w, snippet.String())
+	return err
+}
@@ -0,0 +1,58 @@
+package relations
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/db"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiscoverCandidates(t *testing.T) {
+	columns := []db.Column{
+		{TableName: "users", ColumnName: "id", DataType: "integer", IsPrimaryKey: true, IsUnique: true},
+		{TableName: "users", ColumnName: "email", DataType: "character varying", IsUnique: true},
+		{TableName: "users", ColumnName: "created_at", DataType: "timestamp without time zone"},
+		{TableName: "documents", ColumnName: "id", DataType: "uuid", IsPrimaryKey: true, IsUnique: true},
+		{TableName: "documents", ColumnName: "owner", DataType: "bigint"},
+		{TableName: "documents", ColumnName: "author_email", DataType: "text"},
+		{TableName: "documents", ColumnName: "user_id", DataType: "integer", IsForeignKey: true},
+		{TableName: "documents", ColumnName: "created_at", DataType: "timestamp without time zone"},
+	}
+	expected := []config.Relation{
+		{Table: "documents", Column: "owner", ForeignTable: "users", ForeignColumn: "id"},
+		{Table: "documents", Column: "author_email", ForeignTable: "users", ForeignColumn: "email"},
+	}
+	actual := DiscoverCandidates(columns)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteCandidates(t *testing.T) {
+	candidates := []Candidate{
+		{
+			Relation: config.Relation{Table: "documents", Column: "owner", ForeignTable: "users", ForeignColumn: "id"},
+			Sampled:  40,
+			Coverage: 97.5,
+		},
+	}
+	expected := `settings:
+  relations:
+    # coverage 97.5% of 40 sampled values
+    - table: documents
+      column: owner
+      foreign_table: users
+      foreign_column: id
+`
+	var buf bytes.Buffer
+	err := WriteCandidates(&buf, candidates)
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+	if diff := cmp.Diff(expected, buf.String()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,187 @@
+package relations
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+)
+
+// Suffixes of columns which may reference primary key of other table
+var keySuffixes = []string{"_id", "_uuid"}
+
+var irregularPlurals = map[string]string{
+	"person": "people",
+	"child":  "children",
+}
+
+// Types which can be compared with each other
+var typeFamilies = map[string]string{
+	"smallint":          "integer",
+	"integer":           "integer",
+	"bigint":            "integer",
+	"text":              "text",
+	"character varying": "text",
+	"character":         "text",
+}
+
+// Infer relations from column naming like <singular>_id -> <plural>.id and <table>_uuid -> <table>.<pk>.
+// Candidates that are already declared fks or have different type with target pk are skipped.
+func Infer(columns []db.Column) []config.Relation {
+	pks := getSinglePks(columns)
+	relations := make([]config.Relation, 0)
+	for _, column := range columns {
+		if column.IsForeignKey {
+			continue
+		}
+		for _, targetTable := range candidateTables(column.ColumnName) {
+			pk, ok := pks[targetTable]
+			if !ok || (targetTable == column.TableName && pk.ColumnName == column.ColumnName) {
+				continue
+			}
+			if !IsCompatibleTypes(column.DataType, pk.DataType) {
+				continue
+			}
+			relations = append(relations, config.Relation{
+				Table:         column.TableName,
+				Column:        column.ColumnName,
+				ForeignTable:  pk.TableName,
+				ForeignColumn: pk.ColumnName,
+			})
+			break
+		}
+	}
+	return relations
+}
+
+// Get pk column for tables with not composite pk
+func getSinglePks(columns []db.Column) map[string]db.Column {
+	pks := make(map[string]db.Column)
+	composite := make(map[string]bool)
+	for _, column := range columns {
+		if !column.IsPrimaryKey {
+			continue
+		}
+		if _, ok := pks[column.TableName]; ok {
+			composite[column.TableName] = true
+		}
+		pks[column.TableName] = column
+	}
+	for tableName := range composite {
+		delete(pks, tableName)
+	}
+	return pks
+}
+
+// Get possible referenced table names for column name, most specific first.
+// parent_order_id gives parent_orders, parent_order, orders, order
+func candidateTables(columnName string) []string {
+	var prefix string
+	for _, suffix := range keySuffixes {
+		if strings.HasSuffix(columnName, suffix) {
+			prefix = strings.TrimSuffix(columnName, suffix)
+			break
+		}
+	}
+	if prefix == "" {
+		return nil
+	}
+	tables := make([]string, 0)
+	words := strings.Split(prefix, "_")
+	for i := range words {
+		name := strings.Join(words[i:], "_")
+		if name == "" {
+			continue
+		}
+		tables = append(tables, pluralize(name), name)
+	}
+	return tables
+}
+
+func pluralize(word string) string {
+	if plural, ok := irregularPlurals[word]; ok {
+		return plural
+	}
+	switch {
+	case len(word) > 1 && strings.HasSuffix(word, "y") && !strings.ContainsRune("aeiou", rune(word[len(word)-2])):
+		return word[:len(word)-1] + "ies"
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	default:
+		return word + "s"
+	}
+}
+
+// Check that fk column type can reference pk column type
+func IsCompatibleTypes(columnType string, pkType string) bool {
+	if columnType == pkType {
+		return true
+	}
+	columnFamily, ok := typeFamilies[columnType]
+	if !ok {
+		return false
+	}
+	return columnFamily == typeFamilies[pkType]
+}
+
+// Convert relations of table to fks in the same format as database fks
+func Fks(relations []config.Relation, schemaName string, tableName string, isIncludeIncoming bool) []db.Fk {
+	fks := make([]db.Fk, 0)
+	for _, relation := range relations {
+		if relation.Table == tableName {
+			fks = append(fks, db.Fk{
+				ColumnName:         relation.Column,
+				ForeignTableSchema: schemaName,
+				ForeignTableName:   relation.ForeignTable,
+				ForeignColumnName:  relation.ForeignColumn,
+				Direction:          constants.OUTGOING,
+				ConstraintName:     relation.Name,
+			})
+		}
+		if relation.ForeignTable == tableName && isIncludeIncoming {
+			fks = append(fks, db.Fk{
+				ColumnName:         relation.ForeignColumn,
+				ForeignTableSchema: schemaName,
+				ForeignTableName:   relation.Table,
+				ForeignColumnName:  relation.Column,
+				Direction:          constants.INCOMING,
+				ConstraintName:     relation.Name,
+			})
+		}
+	}
+	return fks
+}
+
+// Write relations as config snippet nested under settings like in config file
+func WriteConfig(w io.Writer, relations []config.Relation) error {
+	sorted := make([]config.Relation, len(relations))
+	copy(sorted, relations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Table != sorted[j].Table {
+			return sorted[i].Table < sorted[j].Table
+		}
+		return sorted[i].Column < sorted[j].Column
+	})
+	var snippet strings.Builder
+	snippet.WriteString(relationsHeader)
+	for _, relation := range sorted {
+		writeRelation(&snippet, relation)
+	}
+	_, err := io.WriteString(w, snippet.String())
+	return err
+}
+
+// Relations are read from settings.relations of config
+const relationsHeader = "settings:\n  relations:\n"
+
+func writeRelation(snippet *strings.Builder, relation config.Relation) {
+	snippet.WriteString(fmt.Sprintf("    - table: %s\n", relation.Table))
+	snippet.WriteString(fmt.Sprintf("      column: %s\n", relation.Column))
+	snippet.WriteString(fmt.Sprintf("      foreign_table: %s\n", relation.ForeignTable))
+	snippet.WriteString(fmt.Sprintf("      foreign_column: %s\n", relation.ForeignColumn))
+}
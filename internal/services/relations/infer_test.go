@@ -0,0 +1,107 @@
+package relations
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/t1m4/db_part_dump/config"
+	"github.com/t1m4/db_part_dump/internal/constants"
+	"github.com/t1m4/db_part_dump/internal/db"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var columns = []db.Column{
+	{TableName: "users", ColumnName: "id", DataType: "integer", IsPrimaryKey: true},
+	{TableName: "users", ColumnName: "company_uuid", DataType: "uuid"},
+	{TableName: "companies", ColumnName: "uuid", DataType: "uuid", IsPrimaryKey: true},
+	{TableName: "orders", ColumnName: "id", DataType: "integer", IsPrimaryKey: true},
+	{TableName: "orders", ColumnName: "user_id", DataType: "bigint"},
+	{TableName: "orders", ColumnName: "parent_order_id", DataType: "integer"},
+	{TableName: "orders", ColumnName: "category_id", DataType: "text"},
+	{TableName: "order_items", ColumnName: "id", DataType: "integer", IsPrimaryKey: true},
+	{TableName: "order_items", ColumnName: "order_id", DataType: "integer", IsForeignKey: true},
+	{TableName: "order_items", ColumnName: "product_id", DataType: "integer"},
+	{TableName: "categories", ColumnName: "id", DataType: "integer", IsPrimaryKey: true},
+}
+
+func TestInfer(t *testing.T) {
+	expected := []config.Relation{
+		{Table: "users", Column: "company_uuid", ForeignTable: "companies", ForeignColumn: "uuid"},
+		{Table: "orders", Column: "user_id", ForeignTable: "users", ForeignColumn: "id"},
+		{Table: "orders", Column: "parent_order_id", ForeignTable: "orders", ForeignColumn: "id"},
+	}
+	actual := Infer(columns)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPluralize(t *testing.T) {
+	type TestData struct {
+		word     string
+		expected string
+	}
+	tests := []TestData{
+		{"user", "users"},
+		{"category", "categories"},
+		{"day", "days"},
+		{"address", "addresses"},
+		{"person", "people"},
+	}
+	for _, test := range tests {
+		actual := pluralize(test.word)
+		if diff := cmp.Diff(test.expected, actual); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestFks(t *testing.T) {
+	relations := []config.Relation{
+		{Table: "orders", Column: "user_id", ForeignTable: "users", ForeignColumn: "id"},
+	}
+	expected := []db.Fk{
+		{
+			ColumnName:         "id",
+			ForeignTableSchema: "alpha",
+			ForeignTableName:   "orders",
+			ForeignColumnName:  "user_id",
+			Direction:          constants.INCOMING,
+		},
+	}
+	actual := Fks(relations, "alpha", "users", true)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	actual = Fks(relations, "alpha", "users", false)
+	if diff := cmp.Diff([]db.Fk{}, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	relations := []config.Relation{
+		{Table: "users", Column: "company_uuid", ForeignTable: "companies", ForeignColumn: "uuid"},
+		{Table: "orders", Column: "user_id", ForeignTable: "users", ForeignColumn: "id"},
+	}
+	expected := `settings:
+  relations:
+    - table: orders
+      column: user_id
+      foreign_table: users
+      foreign_column: id
+    - table: users
+      column: company_uuid
+      foreign_table: companies
+      foreign_column: uuid
+`
+	var buf bytes.Buffer
+	err := WriteConfig(&buf, relations)
+	if err != nil {
+		t.Errorf("wrong err: %v, expected %v", err, nil)
+	}
+	if diff := cmp.Diff(expected, buf.String()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -51,6 +51,13 @@ CREATE TABLE user_preferences (
     language VARCHAR(10) DEFAULT 'en'
 );
 
+-- One-to-one extension of order items
+CREATE TABLE order_item_reviews (
+    id SERIAL PRIMARY KEY,
+    order_item_id INTEGER NOT NULL UNIQUE REFERENCES order_items(id) ON DELETE CASCADE,
+    rating INTEGER NOT NULL
+);
+
 -- Add this to your schema
 CREATE TABLE user_payment_methods (
     id SERIAL PRIMARY KEY,
@@ -115,6 +122,10 @@ ALTER TABLE table_three
     ADD COLUMN one_id UUID,
     ADD CONSTRAINT fk_three_one FOREIGN KEY (one_id) REFERENCES table_one(id);
 
+-- View of rows to start dump from
+CREATE VIEW suspended_users_v AS
+SELECT id AS user_id, username FROM users WHERE status = 'suspended';
+
 
 -- Insert users
 INSERT INTO users (username, email, status, created_at) VALUES
@@ -145,6 +156,11 @@ INSERT INTO order_items (order_id, product_id, product_name, quantity, price) VA
 (6, 107, 'Phone Case', 2, 39.99),
 (7, 108, 'Smartwatch', 1, 199.99);
 
+-- Insert order item reviews
+INSERT INTO order_item_reviews (order_item_id, rating) VALUES
+(1, 5),
+(4, 3);
+
 -- Insert user addresses
 INSERT INTO user_addresses (user_id, address_type, street, city, country) VALUES
 (1, 'home', '123 Main St', 'New York', 'USA'),
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"log"
 	"log/slog"
 	"os"
@@ -10,12 +11,18 @@ import (
 	"github.com/t1m4/db_part_dump/internal/db"
 	"github.com/t1m4/db_part_dump/internal/repositories"
 	"github.com/t1m4/db_part_dump/internal/services/dump"
+	"github.com/t1m4/db_part_dump/internal/services/relations"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	configPath string
+	configPath      string
+	idsFrom         string
+	tenantColumn    string
+	tenant          string
+	requireMasking  bool
+	discoverOptions relations.DiscoverOptions
 )
 
 func main() {
@@ -28,25 +35,68 @@ func main() {
 		Short: "PostgreSQL backup utility with dependency resolution",
 		Run:   RunRoot,
 	}
+	var inferCmd = &cobra.Command{
+		Use:   "infer",
+		Short: "Print relations inferred from column naming as config snippet",
+		Run:   RunInfer,
+	}
+	var discoverCmd = &cobra.Command{
+		Use:   "discover",
+		Short: "Print relations discovered by sampling column values as config snippet",
+		Run:   RunDiscover,
+	}
 
-	rootCmd.Flags().StringVarP(&configPath, "config", "c", "", "Config file path")
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "Config file path")
+	rootCmd.Flags().StringVar(&tenantColumn, "tenant-column", "", "Column of tenant id, every table with it is starting table")
+	rootCmd.Flags().StringVar(&tenant, "tenant", "", "Tenant id which rows are dumped")
+	rootCmd.Flags().BoolVar(&requireMasking, "require-masking", false, "Fail before export when columns with likely PII have no masks")
+	rootCmd.Flags().StringVar(&idsFrom, "ids-from", "", "CSV or newline-delimited file with ids for filter of the only starting table, - is stdin")
+	discoverCmd.Flags().IntVar(&discoverOptions.SampleSize, "sample-size", 100, "Distinct values sampled from every column")
+	discoverCmd.Flags().IntVar(&discoverOptions.MaxChecks, "max-checks", 1000, "Max count of column pairs to check")
+	discoverCmd.Flags().Float64Var(&discoverOptions.MinCoverage, "min-coverage", 90, "Min percentage of values found in foreign column")
+	rootCmd.AddCommand(inferCmd, discoverCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func RunRoot(_ *cobra.Command, args []string) {
+func connect(ctx context.Context) (*config.Config, *sql.DB) {
 	c, err := config.LoadConfig(configPath)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	db, err := db.NewDB(ctx, c)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	return c, db
+}
 
+func RunRoot(_ *cobra.Command, args []string) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	db, err := db.NewDB(ctx, c)
+	c, db := connect(ctx)
+	if idsFrom != "" {
+		err := c.SetIdsFrom(idsFrom)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if tenantColumn != "" || tenant != "" {
+		c.Settings.TenantColumn = tenantColumn
+		c.Settings.Tenant = tenant
+		err := c.Validate()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if requireMasking {
+		c.Settings.RequireMasking = true
+	}
+	err := c.LoadFilterValues(os.Stdin)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatal(err)
 	}
 	repo := repositories.New(db)
 	service := dump.New(c, repo)
@@ -60,3 +110,35 @@ func RunRoot(_ *cobra.Command, args []string) {
 		log.Fatal(err)
 	}
 }
+
+func RunInfer(_ *cobra.Command, args []string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, db := connect(ctx)
+	defer db.Close()
+	repo := repositories.New(db)
+	columns, err := repo.GetColumns(ctx, c.Settings.SchemaName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = relations.WriteConfig(os.Stdout, relations.Infer(columns))
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func RunDiscover(_ *cobra.Command, args []string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, db := connect(ctx)
+	defer db.Close()
+	repo := repositories.New(db)
+	candidates, err := relations.Discover(ctx, repo, c.Settings.SchemaName, discoverOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = relations.WriteCandidates(os.Stdout, candidates)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,45 @@
+// Package transform lets Go users modify or drop exported rows.
+// Transformers are registered in init of custom build and run by every exporter.
+// Transformers must be safe for concurrent use, batch dump calls them from several goroutines
+package transform
+
+import "sync"
+
+// Column metadata of exported row
+type Column struct {
+	Name     string
+	DataType string // Lowercase database type name, e.g. int4, varchar, timestamptz
+}
+
+// Modify row values in place or drop row. Dropped row drops rows which reference it,
+// except rows of fk cycles between different tables which are exported before it
+type RowTransformer interface {
+	// Return false to drop row
+	Transform(tableName string, columns []Column, values []any) (bool, error)
+}
+
+// Adapter to use function as RowTransformer
+type Func func(tableName string, columns []Column, values []any) (bool, error)
+
+func (f Func) Transform(tableName string, columns []Column, values []any) (bool, error) {
+	return f(tableName, columns, values)
+}
+
+var (
+	mu           sync.Mutex
+	transformers []RowTransformer
+)
+
+// Register transformer. Transformers run in registration order
+func Register(transformer RowTransformer) {
+	mu.Lock()
+	defer mu.Unlock()
+	transformers = append(transformers, transformer)
+}
+
+// Get registered transformers
+func Registered() []RowTransformer {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]RowTransformer(nil), transformers...)
+}
@@ -0,0 +1,17 @@
+package transform
+
+import "testing"
+
+func TestRegister(t *testing.T) {
+	Register(Func(func(tableName string, columns []Column, values []any) (bool, error) {
+		return tableName != "audit_log", nil
+	}))
+	registered := Registered()
+	if len(registered) != 1 {
+		t.Fatalf("wrong count of transformers %d", len(registered))
+	}
+	isKept, err := registered[0].Transform("audit_log", nil, nil)
+	if isKept || err != nil {
+		t.Errorf("wrong result %v, %v", isKept, err)
+	}
+}